@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/config"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/service"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// outputFlags 命令行查询共用的输出格式参数
+type outputFlags struct {
+	json    bool
+	csv     bool
+	noColor bool
+}
+
+func (o *outputFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.json, "json", false, "以 JSON 格式输出")
+	fs.BoolVar(&o.csv, "csv", false, "以 CSV 格式输出")
+	fs.BoolVar(&o.noColor, "no-color", false, "关闭颜色")
+}
+
+func (o *outputFlags) validate() error {
+	if o.json && o.csv {
+		return errors.New("--json 和 --csv 不能同时使用")
+	}
+	return nil
+}
+
+// color 判断终端输出是否着色：输出到终端、未设置 NO_COLOR 且未指定 --no-color
+func (o *outputFlags) color() bool {
+	if o.noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// login 使用配置中的账号登录
+func login(ctx context.Context, cfg *config.Config) (*cas.Client, error) {
+	if cfg.CAS.Username == "" || cfg.CAS.Password == "" {
+		return nil, errors.New("未设置账号密码，请通过 -u/-p、QFNU_USER/QFNU_PASS 或配置文件提供")
+	}
+	client, _, err := newClient(cfg, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, loginTimeout(cfg))
+	defer cancel()
+	if err := client.Login(ctx, cfg.CAS.Username, cfg.CAS.Password); err != nil {
+		return nil, fmt.Errorf("登录失败：%w", err)
+	}
+	return client, nil
+}
+
+// prepare 登录并初始化日历和查询服务
+func prepare(ctx context.Context, cfg *config.Config) (*service.ClassroomService, error) {
+	client, err := login(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	serviceCfg := serviceConfig(cfg.Service)
+	if err := service.InitCalendarService(ctx, client, serviceCfg); err != nil {
+		return nil, fmt.Errorf("初始化日历服务失败：%w", err)
+	}
+	return service.NewClassroomService(client, serviceCfg), nil
+}
+
+// buildingArg 教学楼可以通过 -b 指定，也可以作为第一个位置参数
+func buildingArg(building string, fs *flag.FlagSet) (string, error) {
+	if building == "" && fs.NArg() > 0 {
+		building = fs.Arg(0)
+	}
+	if building == "" {
+		return "", errors.New("请指定教学楼，例如 -b 老文史楼")
+	}
+	return building, nil
+}
+
+// runQuery 查询指定节次的空教室
+func runQuery(args []string) error {
+	var (
+		out                 outputFlags
+		building, start, to string
+		offset              int
+	)
+	cfg, fs := loadConfig("query", args, true, func(fs *flag.FlagSet) {
+		fs.StringVar(&building, "b", "", "教学楼名称，例如 老文史楼")
+		fs.StringVar(&start, "start", "01", "起始节次（01-11）")
+		fs.StringVar(&to, "end", "", "终止节次（01-11），默认与起始节次相同")
+		fs.IntVar(&offset, "offset", 0, "日期偏移，0 为今天，1 为明天")
+		out.register(fs)
+	})
+	defer logger.Close()
+	if err := out.validate(); err != nil {
+		return err
+	}
+	building, err := buildingArg(building, fs)
+	if err != nil {
+		return err
+	}
+	if to == "" {
+		to = start
+	}
+
+	ctx := context.Background()
+	cs, err := prepare(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	resp, err := cs.GetEmptyClassrooms(ctx, model.QueryRequest{
+		BuildingName: building,
+		StartNode:    start,
+		EndNode:      to,
+		DateOffset:   offset,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case out.json:
+		return writeJSON(os.Stdout, resp)
+	case out.csv:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"date", "week", "day_of_week", "start_node", "end_node", "room"})
+		for _, room := range resp.Classrooms {
+			w.Write([]string{resp.Date, strconv.Itoa(resp.Week), strconv.Itoa(resp.DayOfWeek), start, to, room})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Printf("%s 第%d周 星期%s 第%s-%s节 %s：共 %d 间空教室\n",
+		resp.Date, resp.Week, weekdayName(resp.DayOfWeek), start, to, building, len(resp.Classrooms))
+	for _, room := range resp.Classrooms {
+		fmt.Println("  " + room)
+	}
+	return nil
+}
+
+// runFullDay 以表格显示教学楼全天教室状态
+func runFullDay(args []string) error {
+	var (
+		out      outputFlags
+		building string
+		offset   int
+	)
+	cfg, fs := loadConfig("fullday", args, true, func(fs *flag.FlagSet) {
+		fs.StringVar(&building, "b", "", "教学楼名称，例如 老文史楼")
+		fs.IntVar(&offset, "offset", 0, "日期偏移，0 为今天，1 为明天")
+		out.register(fs)
+	})
+	defer logger.Close()
+	if err := out.validate(); err != nil {
+		return err
+	}
+	building, err := buildingArg(building, fs)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cs, err := prepare(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	resp, err := cs.GetFullDayStatus(ctx, model.FullDayQueryRequest{BuildingName: building, DateOffset: offset})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case out.json:
+		return writeJSON(os.Stdout, resp)
+	case out.csv:
+		w := csv.NewWriter(os.Stdout)
+		header := []string{"room"}
+		for _, node := range resp.NodeList {
+			header = append(header, node.NodeName)
+		}
+		w.Write(header)
+		for _, room := range resp.Classrooms {
+			row := []string{room.RoomName}
+			for _, st := range room.Status {
+				row = append(row, st.StatusCode)
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Printf("%s %s 第%d周 星期%s %s\n", resp.Building, resp.Date, resp.Week, weekdayName(resp.DayOfWeek), resp.CurrentTerm)
+	if resp.Stale {
+		fmt.Printf("（教务系统暂时不可用，以下为 %s 获取的缓存数据）\n", resp.FetchedAt.Format("15:04:05"))
+	}
+	printGrid(os.Stdout, resp, out.color())
+	return nil
+}
+
+// 全天表格中各状态的显示文字（均为两个字符宽）和颜色
+var gridCells = map[int]struct {
+	label string
+	color string
+}{
+	model.StatusClass:       {"课", logger.ColorRed},
+	model.StatusBorrowed:    {"借", logger.ColorYellow},
+	model.StatusLocked:      {"锁", logger.ColorGray},
+	model.StatusExam:        {"考", logger.ColorPurple},
+	model.StatusFree:        {"空", logger.ColorGreen},
+	model.StatusFixedAdjust: {"固", logger.ColorBlue},
+	model.StatusTempAdjust:  {"临", logger.ColorCyan},
+	model.StatusFullyFree:   {"空", logger.ColorGreen},
+	model.StatusCrossMode:   {"跨", logger.ColorPurple},
+}
+
+// printGrid 输出教室 × 节次的状态表格
+func printGrid(w io.Writer, resp *model.FullDayStatusResponse, color bool) {
+	nameWidth := displayWidth("教室")
+	for _, room := range resp.Classrooms {
+		nameWidth = max(nameWidth, displayWidth(room.RoomName))
+	}
+	colWidths := make([]int, len(resp.NodeList))
+	for i, node := range resp.NodeList {
+		colWidths[i] = max(displayWidth(node.NodeName), 2)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(pad("教室", nameWidth))
+	for i, node := range resp.NodeList {
+		sb.WriteString(" " + pad(node.NodeName, colWidths[i]))
+	}
+	fmt.Fprintln(w, sb.String())
+
+	for _, room := range resp.Classrooms {
+		sb.Reset()
+		sb.WriteString(pad(room.RoomName, nameWidth))
+		for i, st := range room.Status {
+			if i >= len(colWidths) {
+				break
+			}
+			cell, ok := gridCells[st.StatusID]
+			if !ok {
+				cell.label = "？"
+			}
+			text := pad(cell.label, colWidths[i])
+			if color && cell.color != "" {
+				text = cell.color + text + logger.ColorReset
+			}
+			sb.WriteString(" " + text)
+		}
+		fmt.Fprintln(w, sb.String())
+	}
+
+	// 图例
+	sb.Reset()
+	sb.WriteString("\n图例：")
+	for _, id := range []int{model.StatusFree, model.StatusClass, model.StatusBorrowed, model.StatusExam, model.StatusLocked, model.StatusFixedAdjust, model.StatusTempAdjust, model.StatusCrossMode} {
+		cell := gridCells[id]
+		label := cell.label
+		if color {
+			label = cell.color + label + logger.ColorReset
+		}
+		fmt.Fprintf(&sb, " %s=%s", label, model.StatusName(id))
+	}
+	fmt.Fprintln(w, sb.String())
+}
+
+// displayWidth 估算字符串在终端中的显示宽度，中日韩字符和全角字符占两列
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) || (r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFF60) {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// pad 按显示宽度在右侧补空格
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-displayWidth(s), 0))
+}
+
+func weekdayName(day int) string {
+	names := []string{"一", "二", "三", "四", "五", "六", "日"}
+	if day < 1 || day > 7 {
+		return strconv.Itoa(day)
+	}
+	return names[day-1]
+}
+
+// calendarDay calendar 命令输出的单日信息
+type calendarDay struct {
+	Date      string `json:"date"`
+	Week      int    `json:"week"`
+	DayOfWeek int    `json:"day_of_week"`
+}
+
+// runCalendar 显示当前学期、周次和近 7 天的日期信息
+func runCalendar(args []string) error {
+	var out outputFlags
+	cfg, _ := loadConfig("calendar", args, true, func(fs *flag.FlagSet) {
+		out.register(fs)
+	})
+	defer logger.Close()
+	if err := out.validate(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if _, err := prepare(ctx, cfg); err != nil {
+		return err
+	}
+	cal := service.GetCalendarService()
+
+	days := make([]calendarDay, 0, 7)
+	for offset := 0; offset < 7; offset++ {
+		info, date := cal.GetDateInfo(offset)
+		week, _ := strconv.Atoi(info.Zc)
+		day, _ := strconv.Atoi(info.Xq)
+		days = append(days, calendarDay{Date: date, Week: week, DayOfWeek: day})
+	}
+
+	switch {
+	case out.json:
+		return writeJSON(os.Stdout, map[string]any{
+			"current_term":         cal.GetCurrentYearStr(),
+			"current_week":         cal.GetBaseWeek(),
+			"in_teaching_calendar": cal.IsInTeachingCalendar(),
+			"has_permission":       cal.HasPermission(),
+			"days":                 days,
+		})
+	case out.csv:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"date", "week", "day_of_week"})
+		for _, d := range days {
+			w.Write([]string{d.Date, strconv.Itoa(d.Week), strconv.Itoa(d.DayOfWeek)})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Printf("学期：%s\n当前周次：第%d周\n在教学周历内：%v\n有查询权限：%v\n\n",
+		cal.GetCurrentYearStr(), cal.GetBaseWeek(), cal.IsInTeachingCalendar(), cal.HasPermission())
+	for _, d := range days {
+		fmt.Printf("  %s  第%d周  星期%s\n", d.Date, d.Week, weekdayName(d.DayOfWeek))
+	}
+	return nil
+}
+
+// runLoginTest 测试统一认证登录
+func runLoginTest(args []string) error {
+	var out outputFlags
+	cfg, _ := loadConfig("login-test", args, true, func(fs *flag.FlagSet) {
+		out.register(fs)
+	})
+	defer logger.Close()
+	if err := out.validate(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	client, err := login(context.Background(), cfg)
+	elapsed := time.Since(start)
+	if err != nil {
+		if out.json {
+			writeJSON(os.Stdout, map[string]any{"success": false, "error": err.Error(), "elapsed_ms": elapsed.Milliseconds()})
+		}
+		return err
+	}
+
+	state := client.SessionState()
+	switch {
+	case out.json:
+		return writeJSON(os.Stdout, map[string]any{
+			"success":    true,
+			"username":   state.Username,
+			"role":       string(state.Role),
+			"login_at":   state.LoginAt,
+			"elapsed_ms": elapsed.Milliseconds(),
+		})
+	case out.csv:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"success", "username", "role", "elapsed_ms"})
+		w.Write([]string{"true", state.Username, string(state.Role), strconv.FormatInt(elapsed.Milliseconds(), 10)})
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Printf("登录成功：账号 %s，角色 %s，耗时 %v\n", state.Username, state.Role, elapsed.Round(time.Millisecond))
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
@@ -0,0 +1,197 @@
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/service"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// loginTimeout 管理接口触发登录的超时时间
+const loginTimeout = 1 * time.Minute
+
+type Handler struct {
+	client           *cas.Client
+	classroomService *service.ClassroomService
+	captchaSolver    *cas.ManualCaptchaSolver
+	startup          *service.Startup
+}
+
+// NewHandler 创建管理接口处理器
+// captchaSolver 为 nil 表示未启用人工验证码识别
+func NewHandler(client *cas.Client, cs *service.ClassroomService, captchaSolver *cas.ManualCaptchaSolver, startup *service.Startup) *Handler {
+	return &Handler{
+		client:           client,
+		classroomService: cs,
+		captchaSolver:    captchaSolver,
+		startup:          startup,
+	}
+}
+
+// CredentialsRequest 运行时更换账号密码请求
+type CredentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Login    bool   `json:"login"` // 是否立即使用新凭据登录
+}
+
+// CaptchaSubmitRequest 人工提交验证码请求
+type CaptchaSubmitRequest struct {
+	ID     string `json:"id"`     // 验证码挑战 ID
+	Answer string `json:"answer"` // 验证码答案
+}
+
+// GetCaptcha 返回当前等待人工输入的验证码
+func (h *Handler) GetCaptcha(c *gin.Context) {
+	if h.captchaSolver == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未启用人工验证码识别"})
+		return
+	}
+
+	challenge, ok := h.captchaSolver.Pending()
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"pending": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pending":    true,
+		"id":         challenge.ID,
+		"image":      "data:" + http.DetectContentType(challenge.Image) + ";base64," + base64.StdEncoding.EncodeToString(challenge.Image),
+		"created_at": challenge.CreatedAt,
+	})
+}
+
+// SubmitCaptcha 提交人工识别的验证码
+func (h *Handler) SubmitCaptcha(c *gin.Context) {
+	if h.captchaSolver == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未启用人工验证码识别"})
+		return
+	}
+
+	var req CaptchaSubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+
+	if err := h.captchaSolver.Submit(req.ID, req.Answer); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, cas.ErrCaptchaNotPending) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已提交，登录流程继续中"})
+}
+
+// GetSession 返回会话状态
+func (h *Handler) GetSession(c *gin.Context) {
+	state := h.client.SessionState()
+
+	resp := gin.H{
+		"username":       state.Username,
+		"role":           state.Role,
+		"logged_in":      state.LoggedIn,
+		"login_count":    state.LoginCount,
+		"failure_count":  state.FailureCount,
+		"last_failure":   state.LastFailure,
+		"upstream_retry": h.client.Retries(),
+		"has_permission": false,
+	}
+	if !state.LoginAt.IsZero() {
+		resp["login_at"] = state.LoginAt
+		resp["session_age_seconds"] = int(time.Since(state.LoginAt).Seconds())
+	}
+	if !state.LastFailureAt.IsZero() {
+		resp["last_failure_at"] = state.LastFailureAt
+	}
+	if cal := service.GetCalendarService(); cal != nil {
+		resp["has_permission"] = cal.HasPermission()
+	}
+	breakers := gin.H{}
+	for host, st := range h.client.BreakerStates() {
+		breakers[host] = st.String()
+	}
+	resp["breakers"] = breakers
+	resp["startup"] = h.startup.State()
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Login 使用当前凭据重新登录
+func (h *Handler) Login(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), loginTimeout)
+	defer cancel()
+
+	if err := h.client.ReLogin(ctx); err != nil {
+		logger.Warn("管理接口触发登录失败：%v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("管理接口触发登录成功。")
+	// 启动流程还在等待重试时立即继续，不必等到下一次重试
+	h.startup.Retry()
+	c.JSON(http.StatusOK, gin.H{"message": "登录成功"})
+}
+
+// RefreshCalendar 强制刷新日历信息
+func (h *Handler) RefreshCalendar(c *gin.Context) {
+	cal := service.GetCalendarService()
+	if cal == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "日历服务未初始化"})
+		return
+	}
+
+	if err := cal.Refresh(c.Request.Context()); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"current_week":   cal.GetBaseWeek(),
+		"current_term":   cal.GetCurrentYearStr(),
+		"has_permission": cal.HasPermission(),
+	})
+}
+
+// UpdateCredentials 运行时更换账号密码
+func (h *Handler) UpdateCredentials(c *gin.Context) {
+	var req CredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+	if req.Username == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "账号和密码不能为空"})
+		return
+	}
+
+	h.client.SetCredentials(req.Username, req.Password)
+	logger.Info("管理接口已更新登录账号：%s", req.Username)
+
+	if req.Login {
+		// 登录成功后由 Login 通知启动流程；失败时启动流程按原来的间隔用新凭据重试，
+		// 不必立即再用同一凭据登录一次
+		h.Login(c)
+		return
+	}
+	h.startup.Retry()
+	c.JSON(http.StatusOK, gin.H{"message": "凭据已更新，将在下次登录时生效"})
+}
+
+// FlushCache 清空查询缓存
+func (h *Handler) FlushCache(c *gin.Context) {
+	n := h.classroomService.FlushCache()
+	logger.Info("管理接口清空查询缓存，共 %d 条。", n)
+	c.JSON(http.StatusOK, gin.H{"flushed": n})
+}
@@ -0,0 +1,137 @@
+// Package health 提供存活（/healthz）和就绪（/readyz）探针
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Check 单项依赖检查，返回 nil 表示正常
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Result 单项检查结果
+type Result struct {
+	Status     string `json:"status"` // ok / fail
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Handler 存活和就绪探针
+type Handler struct {
+	timeout time.Duration
+	checks  []namedCheck
+	started time.Time
+}
+
+// NewHandler 创建探针处理器，timeout 为全部就绪检查的总超时
+func NewHandler(timeout time.Duration) *Handler {
+	if timeout <= 0 {
+		timeout = config.DefaultReadyTimeout
+	}
+	return &Handler{timeout: timeout, started: time.Now()}
+}
+
+// AddCheck 注册就绪检查项，必须在开始处理请求之前调用
+func (h *Handler) AddCheck(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Healthz 存活探针，进程能处理请求即返回 200，不检查任何依赖
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "ok",
+		"uptime_seconds": int(time.Since(h.started).Seconds()),
+	})
+}
+
+// Readyz 就绪探针，并发执行全部检查，全部通过返回 200，否则返回 503
+// 响应中包含每项检查的结果，便于排查
+func (h *Handler) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	results := make(map[string]Result, len(h.checks))
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, nc := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := run(ctx, nc.check)
+			mu.Lock()
+			results[nc.name] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	ready := true
+	for _, res := range results {
+		if res.Status != "ok" {
+			ready = false
+			break
+		}
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": results})
+}
+
+// run 执行单项检查，超时后不再等待检查返回
+func run(ctx context.Context, check Check) Result {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Status: "ok", DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		res.Status = "fail"
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Cached 缓存检查结果 ttl 时长，用于需要访问外部服务的检查，避免探针过于频繁地请求上游
+// 同一时刻只有一个检查在执行，其余调用等待并复用其结果
+func Cached(check Check, ttl time.Duration) Check {
+	var (
+		mu      sync.Mutex
+		err     error
+		checked time.Time
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < ttl {
+			return err
+		}
+		err = check(ctx)
+		// 调用方超时或取消导致的失败不缓存，下次重新检查
+		if ctx.Err() == nil {
+			checked = time.Now()
+		}
+		return err
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog 以结构化日志记录每个请求，替代 gin.Default 自带的访问日志
+// 需要放在 RequestID 之后，才能带上请求 ID
+// quietPaths 中的路径（例如被频繁探测的 /healthz、/readyz）只以 debug 级别记录
+func AccessLog(quietPaths ...string) gin.HandlerFunc {
+	quiet := make(map[string]bool, len(quietPaths))
+	for _, p := range quietPaths {
+		quiet[p] = true
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"bytes", max(c.Writer.Size(), 0),
+		}
+		if route := c.FullPath(); route != "" {
+			attrs = append(attrs, "route", route)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		ctx := c.Request.Context()
+		switch {
+		case quiet[c.Request.URL.Path]:
+			logger.DebugCtx(ctx, "HTTP 请求", attrs...)
+		case status >= http.StatusInternalServerError:
+			logger.ErrorCtx(ctx, "HTTP 请求", attrs...)
+		case status >= http.StatusBadRequest:
+			logger.WarnCtx(ctx, "HTTP 请求", attrs...)
+		default:
+			logger.InfoCtx(ctx, "HTTP 请求", attrs...)
+		}
+	}
+}
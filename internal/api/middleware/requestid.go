@@ -0,0 +1,38 @@
+// Package middleware 提供 HTTP 接口共用的 gin 中间件
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求 ID 使用的 HTTP 头
+const RequestIDHeader = "X-Request-ID"
+
+// 只接受简单的请求 ID，防止任意内容写入日志
+var reRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID 为每个请求分配请求 ID
+// 客户端或反向代理已携带合法的 X-Request-ID 时沿用，否则随机生成；
+// ID 写入请求 context 和响应头，之后的日志（包括发往教务系统的请求）都会带上它
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !reRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// reTerm 学年学期的格式，如 "2025-2026-1"
+var reTerm = regexp.MustCompile(`^\d{4}-\d{4}-\d$`)
+
+// OccupancyAnalytics 统计教室占用情况
+// GET /api/v1/analytics/occupancy?building=老文史楼&weeks=5-16&term=2025-2026-1
+// 由保存的全天状态快照汇总，不访问教务系统；building 为空时统计全部教学楼，weeks 为空时不限周次，
+// term 为空时使用当前学期
+func (h *Handler) OccupancyAnalytics(c *gin.Context) {
+	term := strings.TrimSpace(c.Query("term"))
+	if term != "" && !reTerm.MatchString(term) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("学期格式错误：%q，应为 2025-2026-1 的形式", term)})
+		return
+	}
+	if term == "" {
+		if cal := service.GetCalendarService(); cal != nil && !cal.LoadedAt().IsZero() {
+			term = cal.GetCurrentYearStr()
+		}
+	}
+	if term == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "学期信息尚未获取，请通过 term 参数指定学期"})
+		return
+	}
+
+	var start, end int
+	if weeks := c.Query("weeks"); weeks != "" {
+		var err error
+		if start, end, err = parseRange(weeks, "周次"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	resp, err := h.classroomService.OccupancyStats(term, strings.TrimSpace(c.Query("building")), start, end)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
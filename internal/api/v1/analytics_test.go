@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOccupancyAnalyticsInvalidTerm(t *testing.T) {
+	r := gin.New()
+	r.GET("/api/v1/analytics/occupancy", NewHandler(nil, nil).OccupancyAnalytics)
+
+	for _, term := range []string{"2025", "2025-2026", "2025-2026-1/../x", "../2025-2026-1", "二〇二五-2026-1"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/occupancy?term="+url.QueryEscape(term), nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("term=%q：状态码 = %d，期望 400", term, w.Code)
+		}
+	}
+}
@@ -1,41 +1,68 @@
 package v1
 
 import (
+	"bytes"
+	"errors"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/export"
 	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
 	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/service"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
 	classroomService *service.ClassroomService
+	startup          *service.Startup
 }
 
-func NewHandler(cs *service.ClassroomService) *Handler {
-	return &Handler{classroomService: cs}
+func NewHandler(cs *service.ClassroomService, startup *service.Startup) *Handler {
+	return &Handler{classroomService: cs, startup: startup}
 }
 
+// startupRetryAfter 启动未完成时建议客户端重试的间隔（秒）
+const startupRetryAfter = 5
+
 // GetStatus 返回系统状态，包括是否在教学周历内
+// 启动未完成时 ready 为 false，message 说明当前阶段（例如正在登录）
 func (h *Handler) GetStatus(c *gin.Context) {
-	cal := service.GetCalendarService()
-	if cal == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":                "日历服务未初始化",
-			"in_teaching_calendar": false,
-			"current_week":         0,
-			"current_term":         "",
-		})
-		return
-	}
-
-	inCalendar := cal.IsInTeachingCalendar()
-	c.JSON(http.StatusOK, gin.H{
-		"in_teaching_calendar": inCalendar,
-		"current_week":         cal.GetBaseWeek(),
-		"current_term":         cal.GetCurrentYearStr(),
-		"has_permission":       cal.HasPermission(),
-	})
+	state := h.startup.State()
+	resp := gin.H{
+		"ready":                state.Phase == service.StartupReady,
+		"message":              state.Message,
+		"startup":              state,
+		"in_teaching_calendar": false,
+		"current_week":         0,
+		"current_term":         "",
+	}
+
+	if cal := service.GetCalendarService(); cal != nil && !cal.LoadedAt().IsZero() {
+		resp["in_teaching_calendar"] = cal.IsInTeachingCalendar()
+		resp["current_week"] = cal.GetBaseWeek()
+		resp["current_term"] = cal.GetCurrentYearStr()
+		resp["has_permission"] = cal.HasPermission()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RequireReady 启动完成前拒绝查询请求，返回 503 并说明原因
+func (h *Handler) RequireReady() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := h.startup.State()
+		if state.Phase != service.StartupReady {
+			c.Header("Retry-After", strconv.Itoa(startupRetryAfter))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": state.Message,
+				"phase": state.Phase,
+			})
+			return
+		}
+		c.Next()
+	}
 }
 
 func (h *Handler) QueryClassrooms(c *gin.Context) {
@@ -55,9 +82,9 @@ func (h *Handler) QueryClassrooms(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.classroomService.GetEmptyClassrooms(req)
+	resp, err := h.classroomService.GetEmptyClassrooms(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -65,6 +92,7 @@ func (h *Handler) QueryClassrooms(c *gin.Context) {
 }
 
 // QueryFullDayStatus 查询全天教室状态
+// 支持 ?format=csv|xlsx 导出表格，?format=ics&room=教室名 导出该教室空闲时间的日历
 func (h *Handler) QueryFullDayStatus(c *gin.Context) {
 	var req model.FullDayQueryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -76,12 +104,103 @@ func (h *Handler) QueryFullDayStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入教学楼名称"})
 		return
 	}
+	format, room, ok := exportParams(c)
+	if !ok {
+		return
+	}
 
-	resp, err := h.classroomService.GetFullDayStatus(req)
+	resp, err := h.classroomService.GetFullDayStatus(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	if format == export.FormatJSON {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	writeExport(c, export.FromFullDay(resp), format, room, resp.Building+"-"+resp.Date)
+}
+
+// exportParams 读取导出参数 ?format=csv|xlsx|ics&room=教室名，ics 格式必须指定教室
+func exportParams(c *gin.Context) (export.Format, string, bool) {
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", "", false
+	}
+	room := strings.TrimSpace(c.Query("room"))
+	if format == export.FormatICS && room == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "导出日历需要通过 room 参数指定教室"})
+		return "", "", false
+	}
+	return format, room, true
+}
+
+// writeExport 以附件形式返回导出文件
+func writeExport(c *gin.Context, g *export.Grid, format export.Format, room, name string) {
+	if format == export.FormatICS {
+		if _, ok := g.Row(room); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "未找到教室 " + room})
+			return
+		}
+		name = room + "-" + name
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, g, format, room); err != nil {
+		respondExportError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": export.Filename(name, format),
+	}))
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// respondExportError 导出失败时的响应，缺少日期无法生成日历时返回 409
+func respondExportError(c *gin.Context, err error) {
+	if errors.Is(err, export.ErrNoDates) {
+		c.JSON(http.StatusConflict, gin.H{"error": "当前不在教学周历内，无法推算各周日期，不能导出日历"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// respondError 将服务层错误转换为 HTTP 响应
+// 上游排队已满或熔断时返回 503 并带上 Retry-After，提示前端稍后重试
+func respondError(c *gin.Context, err error) {
+	var overload *cas.OverloadError
+	if errors.As(err, &overload) {
+		c.Header("Retry-After", strconv.Itoa(int(overload.RetryAfter.Seconds())))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	var open *cas.CircuitOpenError
+	if errors.As(err, &open) {
+		c.Header("Retry-After", strconv.Itoa(int(open.RetryAfter.Seconds())))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrNoSnapshotStore) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrBadSearch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, cas.ErrNoPermission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, cas.ErrSessionExpired) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/export"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRoomWeeks 未指定周次时，从本周起查询的周数
+const defaultRoomWeeks = 5
+
+// RoomSchedule 查询单个教室若干周内每天各节次的状态（周次 × 节次矩阵）
+// GET /api/v1/rooms/:room?weeks=5-16&day=4&start_node=05&end_node=08
+// day 为 4 或 1-5，start_node/end_node 为节次编号，均可省略；
+// 支持 ?format=csv|xlsx 导出表格，?format=ics 导出占用情况日历
+func (h *Handler) RoomSchedule(c *gin.Context) {
+	room := strings.TrimSpace(c.Param("room"))
+	if room == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定教室名称"})
+		return
+	}
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	current := 0
+	if cal := service.GetCalendarService(); cal != nil {
+		current = cal.GetBaseWeek()
+	}
+	start, end, err := parseWeeks(c.Query("weeks"), current)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter, err := roomFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.classroomService.GetRoomSchedule(c.Request.Context(), room, start, end)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	resp = service.FilterRoomSchedule(resp, filter)
+
+	switch format {
+	case export.FormatJSON:
+		c.JSON(http.StatusOK, resp)
+	case export.FormatICS:
+		writeRoomICS(c, resp, "attachment")
+	default:
+		name := fmt.Sprintf("%s-第%d-%d周", resp.Room, resp.StartWeek, resp.EndWeek)
+		writeExport(c, export.FromRoomSchedule(resp), format, "", name)
+	}
+}
+
+// roomFilter 读取星期和节次筛选参数
+func roomFilter(c *gin.Context) (service.RoomFilter, error) {
+	var f service.RoomFilter
+	if day := c.Query("day"); day != "" {
+		from, to, err := parseRange(day, "星期")
+		if err != nil {
+			return f, err
+		}
+		if to > 7 {
+			return f, fmt.Errorf("星期范围无效：%s", day)
+		}
+		f.FromDay, f.ToDay = from, to
+	}
+	for _, p := range []struct {
+		key string
+		dst *int
+	}{{"start_node", &f.StartNode}, {"end_node", &f.EndNode}} {
+		v := c.Query(p.key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 11 {
+			return f, fmt.Errorf("节次格式错误：%s=%s", p.key, v)
+		}
+		*p.dst = n
+	}
+	if f.StartNode > 0 && f.EndNode > 0 && f.EndNode < f.StartNode {
+		return f, fmt.Errorf("终止节次不能早于起始节次")
+	}
+	return f, nil
+}
+
+// RoomCalendar 返回单个教室若干周内被占用时间段的 iCalendar 日历，可在手机日历中订阅
+// GET /api/v1/rooms/:room/calendar.ics?weeks=5-16，未指定 weeks 时为本周起的 5 周
+func (h *Handler) RoomCalendar(c *gin.Context) {
+	room := strings.TrimSpace(c.Param("room"))
+	if room == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定教室名称"})
+		return
+	}
+	cal := service.GetCalendarService()
+	if cal == nil || !cal.IsInTeachingCalendar() {
+		c.JSON(http.StatusConflict, gin.H{"error": "当前不在教学周历内，无法推算各周日期"})
+		return
+	}
+	start, end, err := parseWeeks(c.Query("weeks"), cal.GetBaseWeek())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.classroomService.GetRoomSchedule(c.Request.Context(), room, start, end)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 订阅时日历应用直接读取内容，使用 inline 而不是 attachment
+	writeRoomICS(c, resp, "inline")
+}
+
+// writeRoomICS 返回教室占用情况的日历，disposition 为 inline 或 attachment
+func writeRoomICS(c *gin.Context, resp *model.RoomScheduleResponse, disposition string) {
+	var buf bytes.Buffer
+	if err := export.WriteOccupancyICS(&buf, export.RoomTimeline(resp), resp.Room); err != nil {
+		respondExportError(c, err)
+		return
+	}
+	name := fmt.Sprintf("%s-第%d-%d周", resp.Room, resp.StartWeek, resp.EndWeek)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+		"filename": export.Filename(name, export.FormatICS),
+	}))
+	c.Data(http.StatusOK, export.FormatICS.ContentType(), buf.Bytes())
+}
+
+// parseWeeks 解析周次范围，如 "5-16" 或 "5"；为空时从本周 current 起查询 defaultRoomWeeks 周
+func parseWeeks(s string, current int) (start, end int, err error) {
+	if strings.TrimSpace(s) == "" {
+		if current <= 0 {
+			return 0, 0, fmt.Errorf("当前不在教学周历内，请通过 weeks 参数指定周次")
+		}
+		return current, current + defaultRoomWeeks - 1, nil
+	}
+	start, end, err = parseRange(s, "周次")
+	if err != nil {
+		return 0, 0, err
+	}
+	if end-start+1 > service.MaxRoomWeeks {
+		return 0, 0, fmt.Errorf("一次最多查询 %d 周", service.MaxRoomWeeks)
+	}
+	return start, end, nil
+}
+
+// parseRange 解析 "5-16" 或 "5" 形式的范围，name 用于错误提示
+func parseRange(s, name string) (start, end int, err error) {
+	s = strings.TrimSpace(s)
+	from, to, isRange := strings.Cut(s, "-")
+	start, err = strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s格式错误：%q，应为单个数字或“起-止”，如 5 或 5-16", name, s)
+	}
+	end = start
+	if isRange {
+		end, err = strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s格式错误：%q，应为单个数字或“起-止”，如 5 或 5-16", name, s)
+		}
+	}
+	if start < 1 || end < start {
+		return 0, 0, fmt.Errorf("%s范围无效：%s", name, s)
+	}
+	return start, end, nil
+}
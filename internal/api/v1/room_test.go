@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// TestRoomCalendarWithoutCalendar 日历服务不可用时无法推算各周日期，返回 409 而不是空日历
+func TestRoomCalendarWithoutCalendar(t *testing.T) {
+	r := gin.New()
+	r.GET("/api/v1/rooms/:room/calendar.ics", NewHandler(nil, nil).RoomCalendar)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/rooms/老文史楼101/calendar.ics?weeks=5-6", nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("状态码 = %d，期望 409：%s", w.Code, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), `"error"`) {
+		t.Errorf("响应应包含 error 字段：%s", w.Body)
+	}
+}
+
+// TestWriteRoomICSMissingDates 查询结果缺少日期时返回 409
+func TestWriteRoomICSMissingDates(t *testing.T) {
+	resp := &model.RoomScheduleResponse{
+		Room:      "老文史楼101",
+		StartWeek: 5,
+		EndWeek:   5,
+		NodeList:  []model.NodeInfo{{NodeIndex: 1, NodeName: "0102", StartTime: "08:00", EndTime: "09:50"}},
+		Weeks: []model.RoomWeek{{Week: 5, Days: []model.RoomDay{
+			{DayOfWeek: 1, Status: []model.RoomStatus{{NodeIndex: 1, StatusID: model.StatusClass}}},
+		}}},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	writeRoomICS(c, resp, "inline")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("状态码 = %d，期望 409：%s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("出错时不应返回日历，Content-Type = %s", ct)
+	}
+}
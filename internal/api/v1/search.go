@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSearchLimit 搜索一次最多返回的教室数
+const maxSearchLimit = 200
+
+// maxSearchBuildings 搜索一次最多指定的教学楼数，每个教学楼都要查询一次全天状态
+const maxSearchBuildings = 20
+
+// SearchRooms 跨教学楼搜索指定时间段空闲的教室
+// POST /api/v1/search，可按校区、教学楼、座位数筛选，并按占用节次、座位数或教学楼优先顺序排序
+func (h *Handler) SearchRooms(c *gin.Context) {
+	var req model.SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+
+	start, err1 := strconv.Atoi(req.StartNode)
+	end, err2 := strconv.Atoi(req.EndNode)
+	if err1 != nil || err2 != nil || start < 1 || end > 11 || end < start {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择有效的起始和终止节次"})
+		return
+	}
+	switch req.Sort {
+	case "", service.SortLeastOccupied, service.SortLargest, service.SortBuilding:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort 必须是 least_occupied、largest 或 building"})
+		return
+	}
+	if req.MinSeats < 0 || req.MaxSeats < 0 || (req.MaxSeats > 0 && req.MaxSeats < req.MinSeats) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "座位数范围无效"})
+		return
+	}
+	if len(req.Buildings) > maxSearchBuildings {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "buildings 最多指定 " + strconv.Itoa(maxSearchBuildings) + " 个教学楼"})
+		return
+	}
+	if req.Limit < 0 || req.Limit > maxSearchLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须在 0-" + strconv.Itoa(maxSearchLimit) + " 之间"})
+		return
+	}
+
+	resp, err := h.classroomService.SearchRooms(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListCampuses 返回配置的校区及其教学楼，供搜索页面选择
+func (h *Handler) ListCampuses(c *gin.Context) {
+	type campus struct {
+		Name      string   `json:"name"`
+		Buildings []string `json:"buildings"`
+	}
+	campuses := []campus{}
+	for _, cp := range h.classroomService.Campuses() {
+		campuses = append(campuses, campus{Name: cp.Name, Buildings: cp.Buildings})
+	}
+	c.JSON(http.StatusOK, gin.H{"campuses": campuses})
+}
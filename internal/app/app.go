@@ -0,0 +1,215 @@
+// Package app 管理服务的生命周期：按顺序初始化、启动 HTTP 服务、收到信号后优雅退出
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/config"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// hook 初始化或退出时执行的步骤
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// App 应用生命周期
+//
+// 初始化步骤按注册顺序执行，任一步骤失败即停止启动；
+// 退出步骤按注册的相反顺序执行，先注册的资源最后释放。
+// 退出时先将就绪状态置为 false，再停止接收新请求并等待进行中的请求完成
+// （等待超过退出时间的一半后取消请求的上下文），然后取消后台任务并等待其退出，最后执行退出步骤。
+type App struct {
+	server          *http.Server
+	shutdownTimeout time.Duration
+
+	inits     []hook
+	shutdowns []hook
+
+	// ctx 在退出时取消，后台任务据此停止
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// reqCtx 是 HTTP 请求上下文的父级，退出等待超过宽限时间后取消
+	reqCtx    context.Context
+	reqCancel context.CancelFunc
+
+	ready atomic.Bool
+}
+
+// Option App 配置选项
+type Option func(*App)
+
+// WithShutdownTimeout 设置退出时等待进行中请求和后台任务的最长时间
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(a *App) {
+		if d > 0 {
+			a.shutdownTimeout = d
+		}
+	}
+}
+
+// New 创建 App
+func New(opts ...Option) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+	reqCtx, reqCancel := context.WithCancel(context.Background())
+	a := &App{
+		shutdownTimeout: config.DefaultShutdownTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+		reqCtx:          reqCtx,
+		reqCancel:       reqCancel,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Serve 设置 HTTP 监听地址和处理器，必须在 Run 之前调用；未设置时不启动 HTTP 服务
+func (a *App) Serve(addr string, handler http.Handler) {
+	a.server = &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		// 退出时先让进行中的请求正常完成，超过宽限时间后取消其上下文，
+		// 长时间等待的请求（例如人工验证码）能在退出超时之前结束
+		BaseContext: func(net.Listener) context.Context { return a.reqCtx },
+	}
+}
+
+// OnInit 注册初始化步骤
+func (a *App) OnInit(name string, fn func(ctx context.Context) error) {
+	a.inits = append(a.inits, hook{name: name, fn: fn})
+}
+
+// OnShutdown 注册退出步骤，可以在初始化步骤中调用，使初始化失败时只释放已创建的资源
+func (a *App) OnShutdown(name string, fn func(ctx context.Context) error) {
+	a.shutdowns = append(a.shutdowns, hook{name: name, fn: fn})
+}
+
+// Go 启动后台任务，ctx 在退出时取消，退出流程会等待 fn 返回
+func (a *App) Go(name string, fn func(ctx context.Context)) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("后台任务 %s 异常退出：%v", name, r)
+			}
+		}()
+		fn(a.ctx)
+	}()
+}
+
+// Context 返回 App 的上下文，退出时取消
+func (a *App) Context() context.Context {
+	return a.ctx
+}
+
+// SetReady 设置就绪状态
+func (a *App) SetReady(ready bool) {
+	if a.ready.Swap(ready) != ready {
+		logger.Info("服务就绪状态变更：%v", ready)
+	}
+}
+
+// Ready 返回服务是否就绪，可以接收查询流量
+func (a *App) Ready() bool {
+	return a.ready.Load()
+}
+
+// Run 执行初始化步骤并启动 HTTP 服务，直到 ctx 取消或服务出错，然后优雅退出
+// 通常传入 signal.NotifyContext 返回的 ctx，以便在收到 SIGINT/SIGTERM 时退出
+func (a *App) Run(ctx context.Context) error {
+	for _, h := range a.inits {
+		if err := h.fn(ctx); err != nil {
+			return errors.Join(fmt.Errorf("%s失败：%w", h.name, err), a.shutdown())
+		}
+		if ctx.Err() != nil {
+			return a.shutdown()
+		}
+	}
+
+	serveErr := make(chan error, 1)
+	if a.server != nil {
+		ln, err := net.Listen("tcp", a.server.Addr)
+		if err != nil {
+			return errors.Join(fmt.Errorf("监听 %s 失败：%w", a.server.Addr, err), a.shutdown())
+		}
+		logger.Info("服务器已启动，监听地址：%s", ln.Addr())
+		go func() {
+			if err := a.server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+			close(serveErr)
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		logger.Info("收到退出信号，开始优雅退出")
+	case err = <-serveErr:
+		logger.Error("HTTP 服务异常退出：%v", err)
+	}
+	return errors.Join(err, a.shutdown())
+}
+
+// shutdown 按顺序停止服务、后台任务和已注册的资源
+func (a *App) shutdown() error {
+	a.SetReady(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	// 1. 停止接收新连接，等待进行中的请求完成
+	// 超过退出时间的一半仍未完成的请求，取消其上下文，留出剩余时间让它们返回
+	if a.server != nil {
+		grace := time.AfterFunc(a.shutdownTimeout/2, func() {
+			logger.Warn("仍有请求未完成，取消请求上下文")
+			a.reqCancel()
+		})
+		if err := a.server.Shutdown(ctx); err != nil {
+			logger.Warn("等待进行中的请求超时，强制关闭：%v", err)
+			a.server.Close()
+			errs = append(errs, err)
+		}
+		grace.Stop()
+	}
+	a.reqCancel()
+
+	// 2. 取消后台任务并等待退出
+	a.cancel()
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Warn("等待后台任务退出超时")
+		errs = append(errs, errors.New("等待后台任务退出超时"))
+	}
+
+	// 3. 按相反顺序执行退出步骤，超时后仍然执行，以保证文件等资源被关闭
+	for i := len(a.shutdowns) - 1; i >= 0; i-- {
+		h := a.shutdowns[i]
+		if err := h.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s失败：%w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// TTLCache 带过期时间的内存缓存，并发安全
+// 过期条目会额外保留一段时间，供上游不可用时作为兜底数据读取
+type TTLCache[K comparable, V any] struct {
+	ttl    time.Duration
+	retain time.Duration
+	mu     sync.RWMutex
+	items  map[K]entry[V]
+}
+
+// NewTTLCache 创建缓存
+// ttl: 条目有效期，<= 0 表示不缓存
+// retain: 过期后继续保留的时间，在此期间可通过 GetStale 读取
+func NewTTLCache[K comparable, V any](ttl, retain time.Duration) *TTLCache[K, V] {
+	return &TTLCache[K, V]{
+		ttl:    ttl,
+		retain: retain,
+		items:  make(map[K]entry[V]),
+	}
+}
+
+// Get 获取未过期的缓存值
+func (c *TTLCache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	e, ok := c.items[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(e.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// GetStale 获取缓存值，允许返回已过期但仍在保留期内的条目
+func (c *TTLCache[K, V]) GetStale(key K) (V, bool) {
+	c.mu.RLock()
+	e, ok := c.items[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(e.expiresAt.Add(c.retain)) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set 写入缓存，顺便清理超过保留期的条目
+func (c *TTLCache[K, V]) Set(key K, value V) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.items {
+		if now.After(e.expiresAt.Add(c.retain)) {
+			delete(c.items, k)
+		}
+	}
+	c.items[key] = entry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Flush 清空缓存，返回清除的条目数
+func (c *TTLCache[K, V]) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.items)
+	c.items = make(map[K]entry[V])
+	return n
+}
+
+// Len 返回当前条目数（包含保留期内的过期条目）
+func (c *TTLCache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
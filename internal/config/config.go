@@ -0,0 +1,289 @@
+// Package config 定义服务的全部配置项
+// 加载优先级：默认值 < 配置文件（YAML/TOML） < 环境变量 < 命令行参数
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+)
+
+// Config 服务配置
+type Config struct {
+	Server  ServerConfig  `yaml:"server" toml:"server"`
+	CAS     CASConfig     `yaml:"cas" toml:"cas"`
+	Service ServiceConfig `yaml:"service" toml:"service"`
+	Log     LogConfig     `yaml:"log" toml:"log"`
+	Admin   AdminConfig   `yaml:"admin" toml:"admin"`
+	Store   StoreConfig   `yaml:"store" toml:"store"`
+}
+
+// ServerConfig HTTP 服务配置
+type ServerConfig struct {
+	Port int    `yaml:"port" toml:"port"` // 监听端口
+	Mode string `yaml:"mode" toml:"mode"` // Gin 运行模式：debug / release / test
+
+	// ShutdownTimeout 退出时等待进行中请求和后台任务完成的最长时间
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// ReadyTimeout /readyz 检查（含探测教务系统）的超时，超时视为未就绪
+	ReadyTimeout Duration `yaml:"ready_timeout" toml:"ready_timeout"`
+}
+
+// CASConfig 统一认证登录和教务系统访问配置
+type CASConfig struct {
+	Username string   `yaml:"username" toml:"username"`
+	Password string   `yaml:"password" toml:"password"`
+	Role     string   `yaml:"role" toml:"role"` // teacher / student，为空时自动识别
+	Timeout  Duration `yaml:"timeout" toml:"timeout"`
+
+	CaptchaSolver  string `yaml:"captcha_solver" toml:"captcha_solver"`   // manual / command，为空表示不识别验证码
+	CaptchaCommand string `yaml:"captcha_command" toml:"captcha_command"` // command 模式下执行的命令
+
+	// LoginTimeout 单次登录（含启动时登录和自动重登录）的超时
+	LoginTimeout Duration `yaml:"login_timeout" toml:"login_timeout"`
+	// CaptchaTimeout manual 模式下等待人工输入验证码的时间，登录超时相应延长
+	CaptchaTimeout Duration `yaml:"captcha_timeout" toml:"captcha_timeout"`
+
+	RateLimit   float64 `yaml:"rate_limit" toml:"rate_limit"` // 次/秒，0 表示不限速
+	RateBurst   int     `yaml:"rate_burst" toml:"rate_burst"`
+	MaxInFlight int     `yaml:"max_inflight" toml:"max_inflight"`
+	MaxQueue    int     `yaml:"max_queue" toml:"max_queue"`
+
+	RetryAttempts      int      `yaml:"retry_attempts" toml:"retry_attempts"`
+	BreakerThreshold   int      `yaml:"breaker_threshold" toml:"breaker_threshold"`
+	BreakerOpenTimeout Duration `yaml:"breaker_open_timeout" toml:"breaker_open_timeout"`
+}
+
+// ServiceConfig 查询服务配置
+type ServiceConfig struct {
+	EmptyQueryTimeout      Duration `yaml:"empty_query_timeout" toml:"empty_query_timeout"`
+	FullDayQueryTimeout    Duration `yaml:"full_day_query_timeout" toml:"full_day_query_timeout"`
+	RoomQueryTimeout       Duration `yaml:"room_query_timeout" toml:"room_query_timeout"`
+	CalendarRefreshTimeout Duration `yaml:"calendar_refresh_timeout" toml:"calendar_refresh_timeout"`
+	CacheTTL               Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+	StaleRetain            Duration `yaml:"stale_retain" toml:"stale_retain"`
+
+	// RecommendCount 查询结果中推荐的空教室数，0 表示不推荐
+	RecommendCount int `yaml:"recommend_count" toml:"recommend_count"`
+
+	// Campuses 校区及其教学楼，跨教学楼搜索（POST /api/v1/search）和快照保存只使用这里的教学楼，默认为空
+	Campuses []CampusConfig `yaml:"campuses" toml:"campuses"`
+}
+
+// CampusConfig 校区配置
+type CampusConfig struct {
+	Name      string   `yaml:"name" toml:"name"`
+	Buildings []string `yaml:"buildings" toml:"buildings"`
+}
+
+// LogConfig 日志配置，含义见 logger.Config
+type LogConfig struct {
+	Output     []string `yaml:"output" toml:"output"`
+	Level      string   `yaml:"level" toml:"level"`
+	Format     string   `yaml:"format" toml:"format"`
+	Color      string   `yaml:"color" toml:"color"` // auto / always / never
+	FileLevel  string   `yaml:"file_level" toml:"file_level"`
+	FileFormat string   `yaml:"file_format" toml:"file_format"`
+	Dir        string   `yaml:"dir" toml:"dir"`
+	Prefix     string   `yaml:"prefix" toml:"prefix"`
+	MaxSizeMB  int      `yaml:"max_size_mb" toml:"max_size_mb"`
+	MaxAgeDays int      `yaml:"max_age_days" toml:"max_age_days"`
+	MaxFiles   int      `yaml:"max_files" toml:"max_files"`
+	Compress   bool     `yaml:"compress" toml:"compress"`
+}
+
+// StoreConfig 全天状态快照存储配置
+type StoreConfig struct {
+	Dir string `yaml:"dir" toml:"dir"` // 快照目录，为空（默认）时不保存快照
+}
+
+// AdminConfig 管理接口配置
+type AdminConfig struct {
+	Token string `yaml:"token" toml:"token"` // 为空时管理接口禁用
+}
+
+// Default 返回默认配置
+func Default() Config {
+	retry := cas.DefaultRetryPolicy()
+	breaker := cas.DefaultBreakerConfig()
+	return Config{
+		Server: ServerConfig{
+			Port:            8080,
+			Mode:            gin.DebugMode,
+			ShutdownTimeout: Duration(DefaultShutdownTimeout),
+			ReadyTimeout:    Duration(DefaultReadyTimeout),
+		},
+		CAS: CASConfig{
+			Timeout:            Duration(cas.DefaultTimeout),
+			LoginTimeout:       Duration(cas.DefaultLoginTimeout),
+			CaptchaTimeout:     Duration(DefaultCaptchaTimeout),
+			RateLimit:          5,
+			RateBurst:          10,
+			MaxInFlight:        4,
+			MaxQueue:           32,
+			RetryAttempts:      retry.MaxAttempts,
+			BreakerThreshold:   breaker.FailureThreshold,
+			BreakerOpenTimeout: Duration(breaker.OpenTimeout),
+		},
+		Service: ServiceConfig{
+			EmptyQueryTimeout:      Duration(DefaultEmptyQueryTimeout),
+			FullDayQueryTimeout:    Duration(DefaultFullDayQueryTimeout),
+			RoomQueryTimeout:       Duration(DefaultRoomQueryTimeout),
+			CalendarRefreshTimeout: Duration(DefaultCalendarRefreshTimeout),
+			CacheTTL:               Duration(DefaultCacheTTL),
+			StaleRetain:            Duration(DefaultStaleRetain),
+		},
+		Log: LogConfig{
+			Output:     []string{logger.OutputConsole, logger.OutputFile},
+			Level:      "info",
+			Format:     string(logger.FormatGeek),
+			Color:      "auto",
+			FileLevel:  "debug",
+			FileFormat: string(logger.FormatJSON),
+			Dir:        "logs",
+			Prefix:     "app",
+			MaxSizeMB:  10,
+			MaxAgeDays: 14,
+			MaxFiles:   30,
+			Compress:   true,
+		},
+	}
+}
+
+// Validate 检查配置取值，返回全部问题
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port 必须在 1-65535 之间，当前为 %d", c.Server.Port)
+	check(c.Server.Mode == gin.DebugMode || c.Server.Mode == gin.ReleaseMode || c.Server.Mode == gin.TestMode,
+		"server.mode 必须是 debug、release 或 test，当前为 %q", c.Server.Mode)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout 必须大于 0")
+	check(c.Server.ReadyTimeout > 0, "server.ready_timeout 必须大于 0")
+
+	if _, err := cas.ParseRole(c.CAS.Role); err != nil {
+		errs = append(errs, err)
+	}
+	check(c.CAS.Timeout > 0, "cas.timeout 必须大于 0")
+	check(c.CAS.LoginTimeout > 0, "cas.login_timeout 必须大于 0")
+	check(c.CAS.CaptchaTimeout > 0, "cas.captcha_timeout 必须大于 0")
+	switch c.CAS.CaptchaSolver {
+	case "", "manual":
+	case "command":
+		check(strings.TrimSpace(c.CAS.CaptchaCommand) != "", "cas.captcha_solver 为 command 时必须设置 cas.captcha_command")
+	default:
+		check(false, "cas.captcha_solver 必须是 manual 或 command，当前为 %q", c.CAS.CaptchaSolver)
+	}
+	check(c.CAS.RateLimit >= 0, "cas.rate_limit 不能为负数")
+	check(c.CAS.RateBurst >= 0, "cas.rate_burst 不能为负数")
+	check(c.CAS.MaxInFlight >= 0, "cas.max_inflight 不能为负数")
+	check(c.CAS.MaxQueue >= 0, "cas.max_queue 不能为负数")
+	check(c.CAS.RetryAttempts >= 1, "cas.retry_attempts 至少为 1")
+	check(c.CAS.BreakerThreshold >= 0, "cas.breaker_threshold 不能为负数")
+	check(c.CAS.BreakerThreshold == 0 || c.CAS.BreakerOpenTimeout > 0, "cas.breaker_open_timeout 必须大于 0")
+
+	check(c.Service.EmptyQueryTimeout > 0, "service.empty_query_timeout 必须大于 0")
+	check(c.Service.FullDayQueryTimeout > 0, "service.full_day_query_timeout 必须大于 0")
+	check(c.Service.RoomQueryTimeout > 0, "service.room_query_timeout 必须大于 0")
+	check(c.Service.CalendarRefreshTimeout > 0, "service.calendar_refresh_timeout 必须大于 0")
+	check(c.Service.CacheTTL >= 0, "service.cache_ttl 不能为负数")
+	check(c.Service.StaleRetain >= 0, "service.stale_retain 不能为负数")
+	check(c.Service.RecommendCount >= 0, "service.recommend_count 不能为负数")
+	campuses := make(map[string]bool, len(c.Service.Campuses))
+	for i, campus := range c.Service.Campuses {
+		check(campus.Name != "", "service.campuses[%d].name 不能为空", i)
+		check(!campuses[campus.Name], "service.campuses 中校区 %q 重复", campus.Name)
+		check(len(campus.Buildings) > 0, "service.campuses[%d].buildings 不能为空", i)
+		campuses[campus.Name] = true
+	}
+
+	if _, err := c.Log.LoggerConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// LoggerConfig 转换为 logger.Setup 使用的配置
+func (c LogConfig) LoggerConfig() (logger.Config, error) {
+	cfg := logger.DefaultConfig()
+	var errs []error
+
+	for _, out := range c.Output {
+		switch out {
+		case logger.OutputConsole, logger.OutputFile, logger.OutputNone:
+		default:
+			errs = append(errs, fmt.Errorf("log.output 只能包含 console、file、none，当前为 %q", out))
+		}
+	}
+	cfg.Outputs = c.Output
+
+	var err error
+	if cfg.ConsoleLevel, err = logger.ParseLevel(c.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if cfg.FileLevel, err = logger.ParseLevel(c.FileLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log.file_level: %w", err))
+	}
+	if cfg.ConsoleFormat, err = logger.ParseFormat(c.Format, logger.FormatGeek); err != nil {
+		errs = append(errs, fmt.Errorf("log.format: %w", err))
+	}
+	if cfg.FileFormat, err = logger.ParseFormat(c.FileFormat, logger.FormatJSON); err != nil {
+		errs = append(errs, fmt.Errorf("log.file_format: %w", err))
+	}
+	switch c.Color {
+	case "", "auto":
+	case "always":
+		cfg.NoColor = false
+	case "never":
+		cfg.NoColor = true
+	default:
+		errs = append(errs, fmt.Errorf("log.color 必须是 auto、always 或 never，当前为 %q", c.Color))
+	}
+
+	cfg.Rotation = logger.RotatorOptions{
+		Dir:        c.Dir,
+		Prefix:     c.Prefix,
+		MaxSizeMB:  c.MaxSizeMB,
+		MaxAgeDays: c.MaxAgeDays,
+		MaxFiles:   c.MaxFiles,
+		Compress:   c.Compress,
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// redactedMark 替换敏感配置项的占位符
+const redactedMark = "******"
+
+// Redacted 返回隐去密码、令牌等敏感信息的副本
+func (c Config) Redacted() Config {
+	redact := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return redactedMark
+	}
+	c.CAS.Password = redact(c.CAS.Password)
+	c.Admin.Token = redact(c.Admin.Token)
+	c.Log.Output = append([]string(nil), c.Log.Output...)
+	return c
+}
+
+// Print 以 YAML 格式输出生效的配置，敏感信息已隐去
+func (c Config) Print(w io.Writer) error {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
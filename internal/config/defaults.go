@@ -0,0 +1,24 @@
+package config
+
+import "time"
+
+// 默认值，其他包（app、health、service）的默认行为也以这里为准
+const (
+	// DefaultShutdownTimeout 退出时等待进行中请求和后台任务完成的最长时间
+	DefaultShutdownTimeout = 15 * time.Second
+	// DefaultReadyTimeout 就绪检查（含探测教务系统）的总超时
+	DefaultReadyTimeout = 5 * time.Second
+
+	// DefaultCaptchaTimeout manual 模式下等待管理员在页面上输入验证码的时间
+	DefaultCaptchaTimeout = 10 * time.Minute
+
+	// 上游接口超时，调用方的 ctx 先到期时以调用方为准
+	DefaultEmptyQueryTimeout      = 15 * time.Second // 空教室查询（jsjy_query2，指定节次）
+	DefaultFullDayQueryTimeout    = 20 * time.Second // 全天状态查询（jsjy_query2，全部节次，页面较大）
+	DefaultRoomQueryTimeout       = 30 * time.Second // 单个教室多周状态查询（每周一次请求，共用此超时）
+	DefaultCalendarRefreshTimeout = 20 * time.Second // 刷新日历（学期 + 周次两次请求）
+
+	// 教室占用情况变化不频繁，短时间缓存可以明显减少对教务系统的请求
+	DefaultCacheTTL    = 2 * time.Minute
+	DefaultStaleRetain = 24 * time.Hour // 缓存过期后继续保留的时间，教务系统熔断时用作兜底数据
+)
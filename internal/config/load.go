@@ -0,0 +1,315 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Duration 支持 "30s"、"2m" 这类写法的时间长度，可用于 YAML、TOML 和命令行参数
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Set 实现 flag.Value
+func (d *Duration) Set(s string) error {
+	return d.UnmarshalText([]byte(s))
+}
+
+// stringList 逗号分隔的字符串列表，用于命令行参数
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = splitList(s)
+	return nil
+}
+
+// secret 敏感参数，-h 输出默认值时不显示已配置的内容
+type secret string
+
+func (s *secret) String() string { return "" }
+
+func (s *secret) Set(v string) error {
+	*s = secret(v)
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// ConfigEnv 指定配置文件路径的环境变量，命令行参数 -config 优先
+const ConfigEnv = "QFNU_CONFIG"
+
+// Load 按 默认值 < 配置文件 < 环境变量 < 命令行参数 的优先级加载并校验配置
+// fs 由调用方创建，可以在调用前注册自己的参数；解析后剩余的参数通过 fs.Args() 获取
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	return LoadFrom(Default(), fs, args)
+}
+
+// LoadFrom 与 Load 相同，但以 base 代替 Default() 作为默认值
+// 用于不同子命令需要不同默认值的场景，例如命令行查询默认不写日志文件
+func LoadFrom(base Config, fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := base
+
+	// 1. 配置文件：路径需要在解析参数之前确定
+	path := configPath(args)
+	if path == "" {
+		path = os.Getenv(ConfigEnv)
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. 环境变量
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	// 3. 命令行参数，默认值即为前两步的结果
+	fs.String("config", path, "配置文件路径（.yaml/.yml/.toml），也可通过 "+ConfigEnv+" 指定")
+	bindFlags(fs, &cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("配置无效：\n%w", err)
+	}
+	return &cfg, nil
+}
+
+// configPath 从命令行参数中找出 -config 的值，支持 -config x、-config=x 和双横线写法
+func configPath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// loadFile 按扩展名解析 YAML 或 TOML 配置文件，未知的配置项视为错误，避免拼写错误被静默忽略
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败：%w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalWithOptions(data, cfg, yaml.DisallowUnknownField())
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	default:
+		return fmt.Errorf("不支持的配置文件格式：%s（仅支持 .yaml、.yml、.toml）", path)
+	}
+	if err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败：%w", path, err)
+	}
+	return nil
+}
+
+// envReader 读取环境变量并收集解析错误
+type envReader struct {
+	errs []string
+}
+
+func (e *envReader) add(key, value, kind string) {
+	e.errs = append(e.errs, fmt.Sprintf("环境变量 %s=%q 不是有效的%s", key, value, kind))
+}
+
+func (e *envReader) err() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(e.errs, "；"))
+}
+
+// lookupEnv 依次读取 keys，返回第一个非空的值
+func lookupEnv(keys ...string) (string, string, bool) {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return key, v, true
+		}
+	}
+	return "", "", false
+}
+
+func (e *envReader) String(dst *string, keys ...string) {
+	if _, v, ok := lookupEnv(keys...); ok {
+		*dst = v
+	}
+}
+
+func (e *envReader) Int(dst *int, keys ...string) {
+	if key, v, ok := lookupEnv(keys...); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			e.add(key, v, "整数")
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) Float(dst *float64, keys ...string) {
+	if key, v, ok := lookupEnv(keys...); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			e.add(key, v, "数字")
+			return
+		}
+		*dst = f
+	}
+}
+
+func (e *envReader) Bool(dst *bool, keys ...string) {
+	if key, v, ok := lookupEnv(keys...); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.add(key, v, "布尔值")
+			return
+		}
+		*dst = b
+	}
+}
+
+func (e *envReader) Duration(dst *Duration, keys ...string) {
+	if key, v, ok := lookupEnv(keys...); ok {
+		if err := dst.Set(v); err != nil {
+			e.add(key, v, "时间长度")
+		}
+	}
+}
+
+func (e *envReader) List(dst *[]string, keys ...string) {
+	if _, v, ok := lookupEnv(keys...); ok {
+		*dst = splitList(v)
+	}
+}
+
+// applyEnv 读取环境变量，保留原有的变量名以兼容已有部署
+func applyEnv(cfg *Config) error {
+	var e envReader
+
+	e.Int(&cfg.Server.Port, "PORT")
+	e.String(&cfg.Server.Mode, "GIN_MODE")
+	e.Duration(&cfg.Server.ShutdownTimeout, "QFNU_SHUTDOWN_TIMEOUT")
+	e.Duration(&cfg.Server.ReadyTimeout, "QFNU_READY_TIMEOUT")
+
+	e.String(&cfg.CAS.Username, "QFNU_USER", "QFNU_USERNAME")
+	e.String(&cfg.CAS.Password, "QFNU_PASS", "QFNU_PASSWORD")
+	e.String(&cfg.CAS.Role, "QFNU_ROLE")
+	e.Duration(&cfg.CAS.Timeout, "QFNU_TIMEOUT")
+	e.Duration(&cfg.CAS.LoginTimeout, "QFNU_LOGIN_TIMEOUT")
+	e.Duration(&cfg.CAS.CaptchaTimeout, "QFNU_CAPTCHA_TIMEOUT")
+	e.String(&cfg.CAS.CaptchaSolver, "QFNU_CAPTCHA_SOLVER")
+	e.String(&cfg.CAS.CaptchaCommand, "QFNU_CAPTCHA_COMMAND")
+	e.Float(&cfg.CAS.RateLimit, "QFNU_RATE_LIMIT")
+	e.Int(&cfg.CAS.RateBurst, "QFNU_RATE_BURST")
+	e.Int(&cfg.CAS.MaxInFlight, "QFNU_MAX_INFLIGHT")
+	e.Int(&cfg.CAS.MaxQueue, "QFNU_MAX_QUEUE")
+	e.Int(&cfg.CAS.RetryAttempts, "QFNU_RETRY_ATTEMPTS")
+	e.Int(&cfg.CAS.BreakerThreshold, "QFNU_BREAKER_THRESHOLD")
+	e.Duration(&cfg.CAS.BreakerOpenTimeout, "QFNU_BREAKER_OPEN_TIMEOUT")
+
+	e.Duration(&cfg.Service.EmptyQueryTimeout, "QFNU_QUERY_TIMEOUT")
+	e.Duration(&cfg.Service.FullDayQueryTimeout, "QFNU_FULL_DAY_TIMEOUT")
+	e.Duration(&cfg.Service.RoomQueryTimeout, "QFNU_ROOM_TIMEOUT")
+	e.Duration(&cfg.Service.CalendarRefreshTimeout, "QFNU_CALENDAR_TIMEOUT")
+	e.Duration(&cfg.Service.CacheTTL, "QFNU_CACHE_TTL")
+	e.Duration(&cfg.Service.StaleRetain, "QFNU_STALE_RETAIN")
+	e.Int(&cfg.Service.RecommendCount, "QFNU_RECOMMEND_COUNT")
+
+	e.String(&cfg.Store.Dir, "QFNU_SNAPSHOT_DIR")
+
+	e.List(&cfg.Log.Output, "LOG_OUTPUT")
+	e.String(&cfg.Log.Level, "LOG_LEVEL")
+	e.String(&cfg.Log.Format, "LOG_FORMAT")
+	e.String(&cfg.Log.Color, "LOG_COLOR")
+	e.String(&cfg.Log.FileLevel, "LOG_FILE_LEVEL")
+	e.String(&cfg.Log.FileFormat, "LOG_FILE_FORMAT")
+	e.String(&cfg.Log.Dir, "LOG_DIR")
+	e.String(&cfg.Log.Prefix, "LOG_PREFIX")
+	e.Int(&cfg.Log.MaxSizeMB, "LOG_MAX_SIZE_MB")
+	e.Int(&cfg.Log.MaxAgeDays, "LOG_MAX_AGE_DAYS")
+	e.Int(&cfg.Log.MaxFiles, "LOG_MAX_FILES")
+	e.Bool(&cfg.Log.Compress, "LOG_COMPRESS")
+
+	e.String(&cfg.Admin.Token, "ADMIN_TOKEN")
+	return e.err()
+}
+
+// bindFlags 注册命令行参数
+// 密码可以通过 -p 传入以兼容旧用法，但会留在 shell 历史和进程列表中，建议使用环境变量或配置文件；
+// 管理令牌不提供命令行参数
+func bindFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.IntVar(&cfg.Server.Port, "port", cfg.Server.Port, "HTTP 监听端口")
+	fs.StringVar(&cfg.Server.Mode, "mode", cfg.Server.Mode, "Gin 运行模式（debug/release/test）")
+	fs.Var(&cfg.Server.ShutdownTimeout, "shutdown-timeout", "退出时等待进行中请求完成的最长时间")
+
+	fs.StringVar(&cfg.CAS.Username, "u", cfg.CAS.Username, "统一认证账号")
+	fs.Var((*secret)(&cfg.CAS.Password), "p", "统一认证密码")
+	fs.StringVar(&cfg.CAS.Role, "role", cfg.CAS.Role, "账号角色（teacher/student），为空时自动识别")
+	fs.Var(&cfg.CAS.Timeout, "timeout", "教务系统请求超时")
+	fs.StringVar(&cfg.CAS.CaptchaSolver, "captcha", cfg.CAS.CaptchaSolver, "验证码识别方式（manual/command）")
+	fs.Float64Var(&cfg.CAS.RateLimit, "rate-limit", cfg.CAS.RateLimit, "访问教务系统的速率上限（次/秒），0 表示不限速")
+	fs.IntVar(&cfg.CAS.MaxInFlight, "max-inflight", cfg.CAS.MaxInFlight, "同时进行的教务系统请求数上限")
+	fs.IntVar(&cfg.CAS.RetryAttempts, "retry-attempts", cfg.CAS.RetryAttempts, "教务系统临时故障的最大尝试次数")
+
+	fs.Var(&cfg.Service.CacheTTL, "cache-ttl", "查询结果缓存时间")
+	fs.StringVar(&cfg.Store.Dir, "snapshot-dir", cfg.Store.Dir, "全天状态快照目录，为空时不保存")
+
+	fs.Var((*stringList)(&cfg.Log.Output), "log-output", "日志输出目标，逗号分隔（console/file/none）")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "控制台日志级别（debug/info/warn/error）")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "控制台日志格式（geek/json/logfmt）")
+	fs.StringVar(&cfg.Log.Dir, "log-dir", cfg.Log.Dir, "日志文件目录")
+}
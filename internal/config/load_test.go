@@ -0,0 +1,188 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+)
+
+// TestLoadPrecedence 默认值 < 配置文件 < 环境变量 < 命令行参数
+func TestLoadPrecedence(t *testing.T) {
+	const fileContent = `
+server:
+  port: 9000
+service:
+  cache_ttl: 5m
+log:
+  level: warn
+`
+	tests := []struct {
+		name      string
+		file      bool
+		env       map[string]string
+		args      []string
+		wantPort  int
+		wantTTL   time.Duration
+		wantLevel string
+	}{
+		{
+			name:      "默认值",
+			wantPort:  8080,
+			wantTTL:   DefaultCacheTTL,
+			wantLevel: "info",
+		},
+		{
+			name:      "配置文件覆盖默认值",
+			file:      true,
+			wantPort:  9000,
+			wantTTL:   5 * time.Minute,
+			wantLevel: "warn",
+		},
+		{
+			name:      "环境变量覆盖配置文件",
+			file:      true,
+			env:       map[string]string{"PORT": "9100", "QFNU_CACHE_TTL": "3m"},
+			wantPort:  9100,
+			wantTTL:   3 * time.Minute,
+			wantLevel: "warn",
+		},
+		{
+			name:      "命令行参数覆盖环境变量",
+			file:      true,
+			env:       map[string]string{"PORT": "9100", "QFNU_CACHE_TTL": "3m", "LOG_LEVEL": "error"},
+			args:      []string{"-port", "9200", "-cache-ttl=1m"},
+			wantPort:  9200,
+			wantTTL:   time.Minute,
+			wantLevel: "error",
+		},
+		{
+			name:      "命令行参数覆盖配置文件",
+			file:      true,
+			args:      []string{"-log-level", "debug"},
+			wantPort:  9000,
+			wantTTL:   5 * time.Minute,
+			wantLevel: "debug",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 清空可能影响结果的环境变量，空值视为未设置
+			for _, key := range []string{"PORT", "QFNU_CACHE_TTL", "LOG_LEVEL", ConfigEnv} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			args := tt.args
+			if tt.file {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(fileContent), 0644); err != nil {
+					t.Fatal(err)
+				}
+				args = append([]string{"-config", path}, args...)
+			}
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			cfg, err := Load(fs, args)
+			if err != nil {
+				t.Fatalf("加载配置失败：%v", err)
+			}
+			if cfg.Server.Port != tt.wantPort {
+				t.Errorf("server.port = %d，期望 %d", cfg.Server.Port, tt.wantPort)
+			}
+			if got := time.Duration(cfg.Service.CacheTTL); got != tt.wantTTL {
+				t.Errorf("service.cache_ttl = %v，期望 %v", got, tt.wantTTL)
+			}
+			if cfg.Log.Level != tt.wantLevel {
+				t.Errorf("log.level = %q，期望 %q", cfg.Log.Level, tt.wantLevel)
+			}
+		})
+	}
+}
+
+// TestLoadConfigPath -config 参数优先于 QFNU_CONFIG 环境变量
+func TestLoadConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	envPath := write("env.yaml", "server:\n  port: 9001\n")
+	flagPath := write("flag.toml", "[server]\nport = 9002\n")
+	t.Setenv("PORT", "")
+	t.Setenv(ConfigEnv, envPath)
+
+	for _, tt := range []struct {
+		args []string
+		want int
+	}{
+		{nil, 9001},
+		{[]string{"-config", flagPath}, 9002},
+		{[]string{"--config=" + flagPath}, 9002},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		cfg, err := Load(fs, tt.args)
+		if err != nil {
+			t.Fatalf("%v：加载配置失败：%v", tt.args, err)
+		}
+		if cfg.Server.Port != tt.want {
+			t.Errorf("%v：server.port = %d，期望 %d", tt.args, cfg.Server.Port, tt.want)
+		}
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	t.Setenv(ConfigEnv, "")
+	t.Setenv("PORT", "abc")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err := Load(fs, nil); err == nil {
+		t.Error("PORT 不是整数时应返回错误")
+	}
+}
+
+func TestLoadLoginTimeouts(t *testing.T) {
+	t.Setenv(ConfigEnv, "")
+	for _, key := range []string{"QFNU_LOGIN_TIMEOUT", "QFNU_CAPTCHA_TIMEOUT"} {
+		t.Setenv(key, "")
+	}
+	load := func() (*Config, error) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		return Load(fs, nil)
+	}
+
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CAS.LoginTimeout != Duration(cas.DefaultLoginTimeout) || cfg.CAS.CaptchaTimeout != Duration(DefaultCaptchaTimeout) {
+		t.Errorf("默认 cas.login_timeout, cas.captcha_timeout = %v, %v", cfg.CAS.LoginTimeout, cfg.CAS.CaptchaTimeout)
+	}
+
+	t.Setenv("QFNU_LOGIN_TIMEOUT", "2m")
+	t.Setenv("QFNU_CAPTCHA_TIMEOUT", "5m")
+	if cfg, err = load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CAS.LoginTimeout != Duration(2*time.Minute) || cfg.CAS.CaptchaTimeout != Duration(5*time.Minute) {
+		t.Errorf("cas.login_timeout, cas.captcha_timeout = %v, %v，期望 2m, 5m", cfg.CAS.LoginTimeout, cfg.CAS.CaptchaTimeout)
+	}
+
+	t.Setenv("QFNU_CAPTCHA_TIMEOUT", "0s")
+	if _, err := load(); err == nil {
+		t.Error("cas.captcha_timeout 为 0 时应返回错误")
+	}
+}
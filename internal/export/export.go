@@ -0,0 +1,186 @@
+// Package export 将教室状态导出为 CSV、XLSX 表格和 iCalendar 日历
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatICS  Format = "ics"
+)
+
+// ParseFormat 解析导出格式，空字符串表示 JSON
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatCSV, FormatXLSX, FormatICS:
+		return f, nil
+	}
+	return "", fmt.Errorf("不支持的导出格式 %q，可选 json、csv、xlsx、ics", s)
+}
+
+// ContentType 返回导出格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatICS:
+		return "text/calendar; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Column 表格的一列，对应某天的一个节次
+type Column struct {
+	Label string // 表头，如 "0102" 或 "第5周 0102"
+	Date  string // 日期 YYYY-MM-DD
+	Start string // 上课时间 HH:MM，未知时为空
+	End   string // 下课时间 HH:MM，未知时为空
+}
+
+// Row 表格的一行，Cells 与 Grid.Columns 一一对应
+type Row struct {
+	Name  string
+	Cells []model.RoomStatus
+}
+
+// Grid 教室状态表格，导出时与具体查询方式无关
+// 全天查询为 教室 × 节次，单个教室的多周查询为 周次 × (星期, 节次)，
+// 单个教室的多周时间线为一行，列为各周各天的每个节次
+type Grid struct {
+	Title    string // 工作表名称和日历名称
+	RowLabel string // 第一列的表头，默认为 "教室"
+	Columns  []Column
+	Rows     []Row
+}
+
+// FromFullDay 将全天状态查询结果转换为 教室 × 节次 表格
+func FromFullDay(resp *model.FullDayStatusResponse) *Grid {
+	g := &Grid{Title: fmt.Sprintf("%s %s", resp.Building, resp.Date)}
+	for _, node := range resp.NodeList {
+		g.Columns = append(g.Columns, Column{
+			Label: node.NodeName,
+			Date:  resp.Date,
+			Start: node.StartTime,
+			End:   node.EndTime,
+		})
+	}
+	for _, room := range resp.Classrooms {
+		g.Rows = append(g.Rows, Row{Name: room.RoomName, Cells: room.Status})
+	}
+	return g
+}
+
+// FromRoomSchedule 将单个教室多周状态查询结果转换为 周次 × (星期, 节次) 表格
+// 各周的日期不同，列上不带日期，导出日历请使用 RoomTimeline
+func FromRoomSchedule(resp *model.RoomScheduleResponse) *Grid {
+	g := &Grid{Title: fmt.Sprintf("%s 第%d-%d周", resp.Room, resp.StartWeek, resp.EndWeek), RowLabel: "周次"}
+	if len(resp.Weeks) > 0 {
+		for _, day := range resp.Weeks[0].Days {
+			for _, node := range resp.NodeList {
+				g.Columns = append(g.Columns, Column{
+					Label: weekdayName(day.DayOfWeek) + " " + node.NodeName,
+					Start: node.StartTime,
+					End:   node.EndTime,
+				})
+			}
+		}
+	}
+	for _, week := range resp.Weeks {
+		row := Row{Name: fmt.Sprintf("第%d周", week.Week)}
+		for _, day := range week.Days {
+			row.Cells = append(row.Cells, day.Status...)
+		}
+		g.Rows = append(g.Rows, row)
+	}
+	return g
+}
+
+// RoomTimeline 将单个教室多周状态查询结果转换为只有一行的时间线，每列带有日期，用于导出日历
+func RoomTimeline(resp *model.RoomScheduleResponse) *Grid {
+	g := &Grid{Title: fmt.Sprintf("%s 第%d-%d周", resp.Room, resp.StartWeek, resp.EndWeek), RowLabel: "教室"}
+	row := Row{Name: resp.Room}
+	for _, week := range resp.Weeks {
+		for _, day := range week.Days {
+			for i, node := range resp.NodeList {
+				g.Columns = append(g.Columns, Column{
+					Label: fmt.Sprintf("第%d周 %s %s", week.Week, weekdayName(day.DayOfWeek), node.NodeName),
+					Date:  day.Date,
+					Start: node.StartTime,
+					End:   node.EndTime,
+				})
+				var st model.RoomStatus
+				if i < len(day.Status) {
+					st = day.Status[i]
+				}
+				row.Cells = append(row.Cells, st)
+			}
+		}
+	}
+	g.Rows = []Row{row}
+	return g
+}
+
+// weekdayName 星期的中文名称，day 为 1-7
+func weekdayName(day int) string {
+	names := []string{"周一", "周二", "周三", "周四", "周五", "周六", "周日"}
+	if day < 1 || day > len(names) {
+		return ""
+	}
+	return names[day-1]
+}
+
+// Row 返回名称为 name 的行
+func (g *Grid) Row(name string) (Row, bool) {
+	for _, r := range g.Rows {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Row{}, false
+}
+
+// Write 按格式写出表格，ics 格式需要指定教室 room
+func Write(w io.Writer, g *Grid, f Format, room string) error {
+	switch f {
+	case FormatCSV:
+		return WriteCSV(w, g)
+	case FormatXLSX:
+		return WriteXLSX(w, g)
+	case FormatICS:
+		return WriteFreeICS(w, g, room)
+	}
+	return fmt.Errorf("不支持的导出格式 %q", f)
+}
+
+// Filename 生成下载文件名，去掉文件名中不允许的字符
+func Filename(base string, f Format) string {
+	base = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.ReplaceAll(base, " ", "_"))
+	return base + "." + string(f)
+}
+
+// cellLabel 单元格显示的状态名称
+func cellLabel(st model.RoomStatus) string {
+	if st.StatusID == 0 {
+		return ""
+	}
+	return model.StatusName(st.StatusID)
+}
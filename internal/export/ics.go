@@ -0,0 +1,206 @@
+package export
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+)
+
+// campusTZ 学校所在时区，上下课时间按此解析，写出时带上 TZID
+var campusTZ = time.FixedZone("CST", 8*3600)
+
+// icsTZID 日历中使用的时区，对应的 VTIMEZONE 随日历一起写出
+const icsTZID = "Asia/Shanghai"
+
+// icsProdID 日历的 PRODID
+const icsProdID = "-//W1ndys//easy-qfnu-empty-classrooms//CN"
+
+// ErrNoDates 表格中有的列没有日期，无法生成日历事件（例如不在教学周历内，无法推算各周日期）
+var ErrNoDates = errors.New("缺少日期，无法导出日历")
+
+// checkDates 确认每一列都有日期，避免静默地导出一个空日历
+func checkDates(g *Grid) error {
+	missing := 0
+	for _, col := range g.Columns {
+		if col.Date == "" {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("%w：%d 个节次没有日期", ErrNoDates, missing)
+	}
+	return nil
+}
+
+// event 日历中的一个时间段
+type event struct {
+	summary string
+	start   time.Time
+	end     time.Time
+}
+
+// windows 将一行中相邻且 key 相同的节次合并为时间段
+// key 返回 false 的节次不输出；没有上下课时间的列被跳过，并打断合并
+// 调用前需用 checkDates 确认每一列都有日期
+func windows(g *Grid, row Row, key func(st model.RoomStatus) (string, bool)) []event {
+	var (
+		events []event
+		cur    *event
+		curKey string
+		curDay string
+	)
+	flush := func() {
+		if cur != nil {
+			events = append(events, *cur)
+			cur = nil
+		}
+	}
+
+	for i, col := range g.Columns {
+		if i >= len(row.Cells) {
+			break
+		}
+		k, ok := key(row.Cells[i])
+		start, err1 := time.ParseInLocation("2006-01-02 15:04", col.Date+" "+col.Start, campusTZ)
+		end, err2 := time.ParseInLocation("2006-01-02 15:04", col.Date+" "+col.End, campusTZ)
+		if !ok || err1 != nil || err2 != nil {
+			flush()
+			continue
+		}
+		if cur != nil && k == curKey && col.Date == curDay {
+			cur.end = end
+			continue
+		}
+		flush()
+		cur = &event{summary: k, start: start, end: end}
+		curKey, curDay = k, col.Date
+	}
+	flush()
+	return events
+}
+
+// WriteFreeICS 写出指定教室空闲时间段的日历，相邻的空闲节次合并为一个事件
+func WriteFreeICS(w io.Writer, g *Grid, room string) error {
+	if room == "" {
+		return fmt.Errorf("导出日历需要指定教室")
+	}
+	row, ok := g.Row(room)
+	if !ok {
+		return fmt.Errorf("未找到教室 %s", room)
+	}
+	if err := checkDates(g); err != nil {
+		return err
+	}
+	events := windows(g, row, func(st model.RoomStatus) (string, bool) {
+		return "空闲", model.IsFree(st.StatusID)
+	})
+	for i := range events {
+		events[i].summary = room + " 空闲"
+	}
+	return writeICS(w, room+" 空闲时间", room, events)
+}
+
+// WriteOccupancyICS 写出指定教室被占用时间段的日历，相邻且状态相同的节次合并为一个事件
+// 事件标题为教室和占用类型，如 "老文史楼101 上课"
+func WriteOccupancyICS(w io.Writer, g *Grid, room string) error {
+	if room == "" {
+		return fmt.Errorf("导出日历需要指定教室")
+	}
+	row, ok := g.Row(room)
+	if !ok {
+		return fmt.Errorf("未找到教室 %s", room)
+	}
+	if err := checkDates(g); err != nil {
+		return err
+	}
+	events := windows(g, row, func(st model.RoomStatus) (string, bool) {
+		if st.StatusID == 0 || model.IsFree(st.StatusID) {
+			return "", false
+		}
+		return model.StatusName(st.StatusID), true
+	})
+	for i := range events {
+		events[i].summary = room + " " + events[i].summary
+	}
+	return writeICS(w, room+" 占用情况", room, events)
+}
+
+// writeICS 按 RFC 5545 写出日历，行以 CRLF 结尾，超过 75 字节的行折叠
+func writeICS(w io.Writer, name, location string, events []event) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		bw.WriteString(foldLine(s))
+		bw.WriteString("\r\n")
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:" + icsProdID)
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+	line("X-WR-TIMEZONE:" + icsTZID)
+	// 中国不使用夏令时，一个固定偏移的 STANDARD 即可
+	line("BEGIN:VTIMEZONE")
+	line("TZID:" + icsTZID)
+	line("BEGIN:STANDARD")
+	line("DTSTART:19700101T000000")
+	line("TZOFFSETFROM:+0800")
+	line("TZOFFSETTO:+0800")
+	line("TZNAME:CST")
+	line("END:STANDARD")
+	line("END:VTIMEZONE")
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + eventUID(location, e) + "@easy-qfnu-empty-classrooms")
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;TZID=" + icsTZID + ":" + e.start.In(campusTZ).Format("20060102T150405"))
+		line("DTEND;TZID=" + icsTZID + ":" + e.end.In(campusTZ).Format("20060102T150405"))
+		line("SUMMARY:" + escapeText(e.summary))
+		line("LOCATION:" + escapeText(location))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// eventUID 由教室、时间段和内容生成稳定的 UID，重新订阅时日历应用能识别同一事件
+func eventUID(location string, e event) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d|%d|%s", location, e.start.Unix(), e.end.Unix(), e.summary)))
+	return hex.EncodeToString(sum[:10])
+}
+
+// escapeText 转义 TEXT 类型属性值中的特殊字符
+func escapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+	return r.Replace(s)
+}
+
+// foldLine 将超过 75 字节的行折叠，续行以空格开头，不拆开多字节字符
+func foldLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+)
+
+// unfold 去掉折叠产生的续行，返回各行内容
+func unfold(ics string) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(ics, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+// propValues 返回名称（含参数）为 name 的全部属性值
+func propValues(lines []string, name string) []string {
+	var values []string
+	for _, l := range lines {
+		if v, ok := strings.CutPrefix(l, name+":"); ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// testTimeline 一天四个节次，状态依次为 statuses
+func testTimeline(room, date string, statuses ...int) *Grid {
+	times := [][2]string{{"08:00", "08:50"}, {"09:00", "09:50"}, {"10:10", "11:00"}, {"11:10", "12:00"}}
+	g := &Grid{Title: room}
+	row := Row{Name: room}
+	for i, id := range statuses {
+		g.Columns = append(g.Columns, Column{Date: date, Start: times[i][0], End: times[i][1]})
+		row.Cells = append(row.Cells, model.RoomStatus{StatusID: id})
+	}
+	g.Rows = []Row{row}
+	return g
+}
+
+func TestWriteOccupancyICS(t *testing.T) {
+	g := testTimeline("老文史楼101", "2026-03-02", model.StatusClass, model.StatusClass, model.StatusFree, model.StatusExam)
+	var buf bytes.Buffer
+	if err := WriteOccupancyICS(&buf, g, "老文史楼101"); err != nil {
+		t.Fatal(err)
+	}
+	lines := unfold(buf.String())
+
+	// 相邻且状态相同的节次合并，空闲节次不输出
+	if got, want := propValues(lines, "SUMMARY"), []string{"老文史楼101 正常上课", "老文史楼101 考试"}; !slices.Equal(got, want) {
+		t.Errorf("SUMMARY = %q，期望 %q", got, want)
+	}
+
+	// 时间按学校所在时区写出，并带上 TZID
+	wantStart := []string{"20260302T080000", "20260302T111000"}
+	wantEnd := []string{"20260302T095000", "20260302T120000"}
+	if got := propValues(lines, "DTSTART;TZID=Asia/Shanghai"); !slices.Equal(got, wantStart) {
+		t.Errorf("DTSTART = %q，期望 %q", got, wantStart)
+	}
+	if got := propValues(lines, "DTEND;TZID=Asia/Shanghai"); !slices.Equal(got, wantEnd) {
+		t.Errorf("DTEND = %q，期望 %q", got, wantEnd)
+	}
+	// 引用的时区需要有对应的 VTIMEZONE
+	if got := propValues(lines, "TZID"); !slices.Equal(got, []string{"Asia/Shanghai"}) {
+		t.Errorf("VTIMEZONE 的 TZID = %q", got)
+	}
+	if got := propValues(lines, "TZOFFSETTO"); !slices.Equal(got, []string{"+0800"}) {
+		t.Errorf("TZOFFSETTO = %q，期望 +0800", got)
+	}
+	if i, j := slices.Index(lines, "END:VTIMEZONE"), slices.Index(lines, "BEGIN:VEVENT"); i < 0 || i > j {
+		t.Error("VTIMEZONE 应写在事件之前")
+	}
+
+	// UID 对同一时间段保持不变，重新订阅时不会产生重复事件
+	var again bytes.Buffer
+	if err := WriteOccupancyICS(&again, g, "老文史楼101"); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := propValues(lines, "UID"), propValues(unfold(again.String()), "UID"); len(a) != 2 || !slices.Equal(a, b) {
+		t.Errorf("两次导出的 UID = %q / %q，期望相同", a, b)
+	}
+}
+
+func TestICSEscapeAndFold(t *testing.T) {
+	room := `综合楼A,B;C\D` + "\n" + strings.Repeat("长", 30)
+	g := testTimeline(room, "2026-03-02", model.StatusBorrowed)
+	var buf bytes.Buffer
+	if err := WriteOccupancyICS(&buf, g, room); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("行长度 %d 字节，超过 75：%q", len(l), l)
+		}
+		if !strings.HasPrefix(l, " ") && !strings.Contains(l, ":") {
+			t.Errorf("出现了既不是属性也不是续行的行：%q", l)
+		}
+	}
+
+	want := `综合楼A\,B\;C\\D\n` + strings.Repeat("长", 30)
+	lines := unfold(out)
+	if got := propValues(lines, "LOCATION"); !slices.Equal(got, []string{want}) {
+		t.Errorf("LOCATION = %q，期望 %q", got, want)
+	}
+	if got := propValues(lines, "SUMMARY"); !slices.Equal(got, []string{want + " 借用"}) {
+		t.Errorf("SUMMARY = %q", got)
+	}
+}
+
+func TestWriteICSMissingDates(t *testing.T) {
+	g := testTimeline("老文史楼101", "", model.StatusClass, model.StatusClass)
+	var buf bytes.Buffer
+	err := WriteOccupancyICS(&buf, g, "老文史楼101")
+	if !errors.Is(err, ErrNoDates) {
+		t.Fatalf("缺少日期时返回 %v，期望 ErrNoDates", err)
+	}
+	if buf.Len() != 0 {
+		t.Error("缺少日期时不应写出日历")
+	}
+}
@@ -0,0 +1,162 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/xuri/excelize/v2"
+)
+
+// statusColors 各状态的单元格底色，与前端页面一致（见 docs/full-day-status-feature.md）
+var statusColors = map[int]string{
+	model.StatusClass:       "EF4444",
+	model.StatusBorrowed:    "F97316",
+	model.StatusLocked:      "6B7280",
+	model.StatusExam:        "8B5CF6",
+	model.StatusFree:        "10B981",
+	model.StatusFixedAdjust: "3B82F6",
+	model.StatusTempAdjust:  "06B6D4",
+	model.StatusFullyFree:   "059669",
+	model.StatusCrossMode:   "EC4899",
+}
+
+// header 表头：第一列为行名，之后为各列及其上下课时间
+func header(g *Grid) []string {
+	first := g.RowLabel
+	if first == "" {
+		first = "教室"
+	}
+	row := []string{first}
+	for _, col := range g.Columns {
+		label := col.Label
+		if col.Start != "" {
+			label += " (" + col.Start + "-" + col.End + ")"
+		}
+		row = append(row, label)
+	}
+	return row
+}
+
+// WriteCSV 写出 CSV 表格，单元格为状态名称
+// 开头写入 UTF-8 BOM，Excel 直接打开时中文不会乱码
+func WriteCSV(w io.Writer, g *Grid) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(header(g))
+	for _, r := range g.Rows {
+		record := make([]string, 0, len(g.Columns)+1)
+		record = append(record, r.Name)
+		for i := range g.Columns {
+			var st model.RoomStatus
+			if i < len(r.Cells) {
+				st = r.Cells[i]
+			}
+			record = append(record, cellLabel(st))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteXLSX 写出 XLSX 表格，单元格为状态名称并按状态着色，末尾附图例
+func WriteXLSX(w io.Writer, g *Grid) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := sheetName(g.Title)
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	headStyle, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+	})
+	if err != nil {
+		return err
+	}
+	styles := make(map[int]int, len(statusColors))
+	for id, color := range statusColors {
+		style, err := f.NewStyle(&excelize.Style{
+			Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{color}},
+			Font:      &excelize.Font{Color: "FFFFFF"},
+			Alignment: &excelize.Alignment{Horizontal: "center"},
+		})
+		if err != nil {
+			return err
+		}
+		styles[id] = style
+	}
+
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	sw.SetColWidth(1, 1, 20)
+	sw.SetColWidth(2, len(g.Columns)+1, 14)
+
+	head := header(g)
+	cells := make([]any, len(head))
+	for i, v := range head {
+		cells[i] = excelize.Cell{StyleID: headStyle, Value: v}
+	}
+	if err := sw.SetRow("A1", cells); err != nil {
+		return err
+	}
+
+	rowNum := 2
+	for _, r := range g.Rows {
+		cells := make([]any, 0, len(g.Columns)+1)
+		cells = append(cells, r.Name)
+		for i := range g.Columns {
+			var st model.RoomStatus
+			if i < len(r.Cells) {
+				st = r.Cells[i]
+			}
+			cells = append(cells, excelize.Cell{StyleID: styles[st.StatusID], Value: cellLabel(st)})
+		}
+		axis, _ := excelize.CoordinatesToCellName(1, rowNum)
+		if err := sw.SetRow(axis, cells); err != nil {
+			return err
+		}
+		rowNum++
+	}
+
+	// 图例
+	rowNum++
+	for id := model.StatusClass; id <= model.StatusCrossMode; id++ {
+		axis, _ := excelize.CoordinatesToCellName(1, rowNum)
+		legend := []any{excelize.Cell{StyleID: styles[id], Value: model.StatusName(id)}}
+		if err := sw.SetRow(axis, legend); err != nil {
+			return err
+		}
+		rowNum++
+	}
+
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// sheetName 工作表名称最长 31 个字符，且不能包含 []:*?/\
+func sheetName(title string) string {
+	var out []rune
+	for _, r := range title {
+		switch r {
+		case '[', ']', ':', '*', '?', '/', '\\':
+			r = '_'
+		}
+		out = append(out, r)
+		if len(out) == 31 {
+			break
+		}
+	}
+	if len(out) == 0 {
+		return "Sheet1"
+	}
+	return string(out)
+}
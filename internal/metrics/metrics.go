@@ -0,0 +1,184 @@
+// Package metrics 定义服务的 Prometheus 指标，并提供 gin 中间件和 cas.Observer 实现
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "qfnu"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "按路由、方法和状态码统计的 HTTP 请求数",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "按路由统计的 HTTP 请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "按接口和状态码统计的教务系统/统一认证请求数，请求失败时 status 为 error",
+	}, []string{"endpoint", "status"})
+
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "按接口统计的上游请求耗时（不含本地排队时间）",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2, 5, 10, 20},
+	}, []string{"endpoint"})
+
+	upstreamRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "按接口统计的上游临时故障重试次数",
+	}, []string{"endpoint"})
+
+	logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "登录次数，result 为 success 或 failure",
+	}, []string{"result"})
+
+	loginDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "login_duration_seconds",
+		Help:      "完整登录流程耗时",
+		Buckets:   []float64{.5, 1, 2, 5, 10, 30, 60},
+	})
+
+	reLogins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relogins_total",
+		Help:      "Session 失效后的重新登录次数，result 为 success 或 failure",
+	}, []string{"result"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "查询缓存访问次数，result 为 hit、miss 或 stale（熔断时返回过期数据）",
+	}, []string{"cache", "result"})
+
+	calendarWeek = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "calendar_week",
+		Help:      "日历服务当前的教学周次，不在教学周历内时为 0",
+	})
+
+	calendarTerm = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "calendar_term_info",
+		Help:      "日历服务当前的学年学期，值恒为 1",
+	}, []string{"term"})
+
+	parseFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parse_failures_total",
+		Help:      "按解析器统计的上游页面解析失败次数",
+	}, []string{"parser"})
+
+	unknownStatusCodes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unknown_status_codes_total",
+		Help:      "全天状态表中出现的未知状态码",
+	}, []string{"code"})
+)
+
+// Middleware 统计每个路由的请求数和耗时
+// 未匹配路由统一记为 unmatched，避免任意路径导致标签基数膨胀
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(route, method, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// CASObserver 将 cas.Client 的内部事件转换为指标
+type CASObserver struct{}
+
+var _ cas.Observer = CASObserver{}
+
+// ObserveUpstream 实现 cas.Observer
+func (CASObserver) ObserveUpstream(endpoint string, status int, err error, d time.Duration) {
+	label := "error"
+	if err == nil {
+		label = strconv.Itoa(status)
+	}
+	upstreamRequests.WithLabelValues(endpoint, label).Inc()
+	upstreamDuration.WithLabelValues(endpoint).Observe(d.Seconds())
+}
+
+// ObserveRetry 实现 cas.Observer
+func (CASObserver) ObserveRetry(endpoint string) {
+	upstreamRetries.WithLabelValues(endpoint).Inc()
+}
+
+// ObserveLogin 实现 cas.Observer
+func (CASObserver) ObserveLogin(err error, d time.Duration) {
+	logins.WithLabelValues(result(err)).Inc()
+	loginDuration.Observe(d.Seconds())
+}
+
+// ObserveReLogin 实现 cas.Observer
+func (CASObserver) ObserveReLogin(err error) {
+	reLogins.WithLabelValues(result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// CacheHit 记录一次缓存命中
+func CacheHit(cache string) { cacheRequests.WithLabelValues(cache, "hit").Inc() }
+
+// CacheMiss 记录一次缓存未命中
+func CacheMiss(cache string) { cacheRequests.WithLabelValues(cache, "miss").Inc() }
+
+// CacheStale 记录一次熔断兜底返回的过期缓存
+func CacheStale(cache string) { cacheRequests.WithLabelValues(cache, "stale").Inc() }
+
+// SetCalendar 更新日历周次和学期
+func SetCalendar(term string, week int) {
+	calendarWeek.Set(float64(week))
+	calendarTerm.Reset()
+	calendarTerm.WithLabelValues(term).Set(1)
+}
+
+// ParseFailure 记录一次页面解析失败
+func ParseFailure(parser string) {
+	parseFailures.WithLabelValues(parser).Inc()
+}
+
+// maxCodeLen 未知状态码标签的最大长度，防止异常页面内容成为标签
+const maxCodeLen = 8
+
+// UnknownStatusCode 记录一次未知的教室状态码
+func UnknownStatusCode(code string) {
+	if r := []rune(code); len(r) > maxCodeLen {
+		code = string(r[:maxCodeLen])
+	}
+	unknownStatusCodes.WithLabelValues(code).Inc()
+}
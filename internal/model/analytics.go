@@ -0,0 +1,49 @@
+package model
+
+// Occupancy 一组节次的占用统计
+type Occupancy struct {
+	Observed int     `json:"observed"` // 有记录的节次数
+	Occupied int     `json:"occupied"` // 被占用（非空闲）的节次数
+	Rate     float64 `json:"rate"`     // 占用率 (0-1)
+	Exam     int     `json:"exam"`     // 考试（Κ）节次数
+	Borrowed int     `json:"borrowed"` // 借用（Ｊ）节次数
+}
+
+// RoomOccupancy 单个教室的占用统计
+type RoomOccupancy struct {
+	RoomName string `json:"room_name"`       // 教室名称
+	Seats    int    `json:"seats,omitempty"` // 座位数
+	Occupancy
+}
+
+// NodeOccupancy 单个节次的占用统计
+type NodeOccupancy struct {
+	NodeName  string `json:"node_name"`            // 节次名称 (如 "0102")
+	StartTime string `json:"start_time,omitempty"` // 上课时间
+	EndTime   string `json:"end_time,omitempty"`   // 下课时间
+	Occupancy
+}
+
+// WeekdayOccupancy 某个星期几的占用统计，Nodes 构成 星期 × 节次 热力图的一行
+type WeekdayOccupancy struct {
+	DayOfWeek int             `json:"day_of_week"`        // 星期几 (1-7)
+	Days      int             `json:"days"`               // 统计到的天数
+	Rate      float64         `json:"rate"`               // 当天整体占用率
+	Busiest   *NodeOccupancy  `json:"busiest,omitempty"`  // 占用率最高的节次
+	Quietest  *NodeOccupancy  `json:"quietest,omitempty"` // 占用率最低的节次
+	Nodes     []NodeOccupancy `json:"nodes"`              // 各节次的占用统计
+}
+
+// OccupancyResponse 占用统计响应，由保存的全天状态快照汇总而来
+type OccupancyResponse struct {
+	Term      string             `json:"term"`       // 学年学期
+	Building  string             `json:"building"`   // 教学楼名称，为空表示全部有快照的教学楼
+	StartWeek int                `json:"start_week"` // 起始周次，0 表示不限
+	EndWeek   int                `json:"end_week"`   // 终止周次，0 表示不限
+	Days      int                `json:"days"`       // 统计到的 教学楼 × 日期 快照数
+	Dates     []string           `json:"dates"`      // 统计到的日期
+	Overall   Occupancy          `json:"overall"`    // 整体占用统计
+	Rooms     []RoomOccupancy    `json:"rooms"`      // 各教室，按占用率从高到低
+	Nodes     []NodeOccupancy    `json:"nodes"`      // 各节次
+	Weekdays  []WeekdayOccupancy `json:"weekdays"`   // 各星期几
+}
@@ -0,0 +1,41 @@
+package model
+
+// Period 单个节次的上下课时间
+type Period struct {
+	Node  string // 节次编号，两位数字，如 "01"
+	Start string // 上课时间 HH:MM
+	End   string // 下课时间 HH:MM
+}
+
+// Periods 作息时间表，与教务系统全天状态表头的 tdKssj/tdJssj 一致
+// 表头未带时间时用于推算节次的起止时间
+var Periods = []Period{
+	{"01", "08:00", "08:50"},
+	{"02", "09:00", "09:50"},
+	{"03", "10:10", "11:00"},
+	{"04", "11:10", "12:00"},
+	{"05", "14:30", "15:20"},
+	{"06", "15:30", "16:20"},
+	{"07", "16:30", "17:20"},
+	{"08", "17:30", "18:20"},
+	{"09", "19:00", "19:50"},
+	{"10", "20:00", "20:50"},
+	{"11", "21:00", "21:50"},
+}
+
+// PeriodTimes 返回节次组的起止时间，group 为连续的两位节次编号，如 "0102"、"091011"
+func PeriodTimes(group string) (start, end string, ok bool) {
+	if len(group) < 2 || len(group)%2 != 0 {
+		return "", "", false
+	}
+	first, last := group[:2], group[len(group)-2:]
+	for _, p := range Periods {
+		if p.Node == first {
+			start = p.Start
+		}
+		if p.Node == last {
+			end = p.End
+		}
+	}
+	return start, end, start != "" && end != ""
+}
@@ -0,0 +1,39 @@
+package model
+
+// 教室状态 ID，与 mapStatusCodeToID 的映射一致
+const (
+	StatusClass       = 1 // ◆ 正常上课
+	StatusBorrowed    = 2 // Ｊ 借用
+	StatusLocked      = 3 // Ｘ 锁定
+	StatusExam        = 4 // Κ 考试
+	StatusFree        = 5 // 空闲
+	StatusFixedAdjust = 6 // Ｇ 固定调课
+	StatusTempAdjust  = 7 // Ｌ 临时调课
+	StatusFullyFree   = 8 // 完全空闲
+	StatusCrossMode   = 9 // M 跨模式占用
+)
+
+var statusNames = map[int]string{
+	StatusClass:       "正常上课",
+	StatusBorrowed:    "借用",
+	StatusLocked:      "锁定",
+	StatusExam:        "考试",
+	StatusFree:        "空闲",
+	StatusFixedAdjust: "固定调课",
+	StatusTempAdjust:  "临时调课",
+	StatusFullyFree:   "完全空闲",
+	StatusCrossMode:   "跨模式占用",
+}
+
+// StatusName 返回状态 ID 对应的中文名称
+func StatusName(id int) string {
+	if name, ok := statusNames[id]; ok {
+		return name
+	}
+	return "未知"
+}
+
+// IsFree 判断该状态是否可以使用（空闲或完全空闲）
+func IsFree(id int) bool {
+	return id == StatusFree || id == StatusFullyFree
+}
@@ -1,19 +1,25 @@
 package model
 
+import "time"
+
 // QueryRequest 前端查询请求参数
 type QueryRequest struct {
-	BuildingName string `json:"building"`     // 教学楼名称 (如 "老文史楼")
-	StartNode    string `json:"start_node"`   // 起始节次 (如 "01")
-	EndNode      string `json:"end_node"`     // 终止节次 (如 "02")
-	DateOffset   int    `json:"date_offset"`  // 日期偏移 (0=今天, 1=明天...)
+	BuildingName string `json:"building"`    // 教学楼名称 (如 "老文史楼")
+	StartNode    string `json:"start_node"`  // 起始节次 (如 "01")
+	EndNode      string `json:"end_node"`    // 终止节次 (如 "02")
+	DateOffset   int    `json:"date_offset"` // 日期偏移 (0=今天, 1=明天...)
 }
 
 // ClassroomResponse 返回给前端的响应
 type ClassroomResponse struct {
-	Date       string   `json:"date"`        // 查询日期 (YYYY-MM-DD)
-	Week       int      `json:"week"`        // 教学周
-	DayOfWeek  int      `json:"day_of_week"` // 星期几
-	Classrooms []string `json:"classrooms"`  // 空教室列表
+	Date       string    `json:"date"`        // 查询日期 (YYYY-MM-DD)
+	Week       int       `json:"week"`        // 教学周
+	DayOfWeek  int       `json:"day_of_week"` // 星期几
+	Classrooms []string  `json:"classrooms"`  // 空教室列表
+	FetchedAt  time.Time `json:"fetched_at"`  // 数据从教务系统获取的时间
+	Stale      bool      `json:"stale"`       // 是否为教务系统不可用时返回的历史数据
+
+	Recommend []Recommendation `json:"recommend,omitempty"` // 推荐的空教室，按得分从高到低
 }
 
 // CalendarInfo 内部使用的日历信息
@@ -38,8 +44,10 @@ type ClassroomStatus struct {
 
 // NodeInfo 节次信息
 type NodeInfo struct {
-	NodeIndex int    `json:"node_index"` // 节次索引 (1-11)
-	NodeName  string `json:"node_name"`  // 节次名称 (如 "第1节")
+	NodeIndex int    `json:"node_index"`           // 节次索引 (1-11)
+	NodeName  string `json:"node_name"`            // 节次名称 (如 "第1节")
+	StartTime string `json:"start_time,omitempty"` // 上课时间 (如 "08:00")
+	EndTime   string `json:"end_time,omitempty"`   // 下课时间 (如 "09:50")
 }
 
 // RoomStatus 单个教室在单个节次的状态
@@ -51,17 +59,102 @@ type RoomStatus struct {
 
 // ClassroomFullStatus 单个教室的全天状态
 type ClassroomFullStatus struct {
-	RoomName string       `json:"room_name"` // 教室名称 (如 "老文史楼101")
-	Status   []RoomStatus `json:"status"`    // 各节次状态列表
+	RoomName  string       `json:"room_name"`            // 教室名称 (如 "老文史楼101")
+	Seats     int          `json:"seats,omitempty"`      // 座位数
+	ExamSeats int          `json:"exam_seats,omitempty"` // 考试座位数
+	Status    []RoomStatus `json:"status"`               // 各节次状态列表
 }
 
 // FullDayStatusResponse 全天状态查询响应
 type FullDayStatusResponse struct {
-	Date       string                `json:"date"`        // 查询日期 (YYYY-MM-DD)
-	Week       int                   `json:"week"`        // 教学周
-	DayOfWeek  int                   `json:"day_of_week"` // 星期几 (1-7)
-	CurrentTerm string               `json:"current_term"` // 当前学期 (2025-2026-1)
-	Building   string                `json:"building"`    // 教学楼名称
-	NodeList   []NodeInfo            `json:"node_list"`   // 节次列表（用于前端表头）
-	Classrooms []ClassroomFullStatus `json:"classrooms"`  // 各教室全天状态列表
+	Date        string                `json:"date"`         // 查询日期 (YYYY-MM-DD)
+	Week        int                   `json:"week"`         // 教学周
+	DayOfWeek   int                   `json:"day_of_week"`  // 星期几 (1-7)
+	CurrentTerm string                `json:"current_term"` // 当前学期 (2025-2026-1)
+	Building    string                `json:"building"`     // 教学楼名称
+	NodeList    []NodeInfo            `json:"node_list"`    // 节次列表（用于前端表头）
+	Classrooms  []ClassroomFullStatus `json:"classrooms"`   // 各教室全天状态列表
+	FetchedAt   time.Time             `json:"fetched_at"`   // 数据从教务系统获取的时间
+	Stale       bool                  `json:"stale"`        // 是否为教务系统不可用时返回的历史数据
+}
+
+// RoomDay 单个教室某一天的各节次状态
+type RoomDay struct {
+	DayOfWeek int          `json:"day_of_week"`    // 星期几 (1-7)
+	Date      string       `json:"date,omitempty"` // 日期 (YYYY-MM-DD)，不在教学周历内时为空
+	Status    []RoomStatus `json:"status"`         // 各节次状态，与 NodeList 一一对应
+}
+
+// RoomWeek 单个教室某一周的状态
+type RoomWeek struct {
+	Week int       `json:"week"` // 教学周
+	Days []RoomDay `json:"days"` // 周一至周日
+}
+
+// RoomScheduleResponse 单个教室多周状态查询响应
+type RoomScheduleResponse struct {
+	Room        string     `json:"room"`         // 教室名称 (如 "老文史楼101")
+	CurrentTerm string     `json:"current_term"` // 当前学期 (2025-2026-1)
+	StartWeek   int        `json:"start_week"`   // 起始周次
+	EndWeek     int        `json:"end_week"`     // 终止周次
+	NodeList    []NodeInfo `json:"node_list"`    // 节次列表（每天相同）
+	Weeks       []RoomWeek `json:"weeks"`        // 各周状态
+	Free        bool       `json:"free"`         // 所选范围内的节次是否全部空闲
+	FetchedAt   time.Time  `json:"fetched_at"`   // 最早一周数据从教务系统获取的时间
+	Stale       bool       `json:"stale"`        // 是否有某周为教务系统不可用时返回的历史数据
+}
+
+// SearchRequest 跨教学楼搜索空教室的请求
+type SearchRequest struct {
+	DateOffset       int      `json:"date_offset"`       // 日期偏移 (0=今天, 1=明天...)
+	StartNode        string   `json:"start_node"`        // 起始节次 (如 "05")
+	EndNode          string   `json:"end_node"`          // 终止节次 (如 "06")
+	Campus           string   `json:"campus"`            // 校区名称，为空表示不限
+	Buildings        []string `json:"buildings"`         // 教学楼名称，为空表示校区内（或全部配置的）教学楼
+	MinSeats         int      `json:"min_seats"`         // 最少座位数，0 表示不限
+	MaxSeats         int      `json:"max_seats"`         // 最多座位数，0 表示不限
+	Sort             string   `json:"sort"`              // 排序方式：least_occupied / largest / building
+	BuildingPriority []string `json:"building_priority"` // sort 为 building 时教学楼的优先顺序
+	Limit            int      `json:"limit"`             // 最多返回的教室数
+}
+
+// SearchResult 搜索到的单个空教室
+type SearchResult struct {
+	RoomName      string       `json:"room_name"`        // 教室名称
+	Building      string       `json:"building"`         // 所属教学楼（查询时使用的名称）
+	Campus        string       `json:"campus,omitempty"` // 所属校区
+	Seats         int          `json:"seats"`            // 座位数
+	ExamSeats     int          `json:"exam_seats"`       // 考试座位数
+	OccupiedNodes int          `json:"occupied_nodes"`   // 当天被占用的节次数
+	Status        []RoomStatus `json:"status"`           // 当天各节次状态
+}
+
+// SearchResponse 跨教学楼搜索空教室的响应
+type SearchResponse struct {
+	Date      string            `json:"date"`             // 查询日期 (YYYY-MM-DD)
+	Week      int               `json:"week"`             // 教学周
+	DayOfWeek int               `json:"day_of_week"`      // 星期几 (1-7)
+	StartNode string            `json:"start_node"`       // 起始节次
+	EndNode   string            `json:"end_node"`         // 终止节次
+	Sort      string            `json:"sort"`             // 实际使用的排序方式
+	NodeList  []NodeInfo        `json:"node_list"`        // 节次列表，与各结果的 status 对应
+	Total     int               `json:"total"`            // 符合条件的教室总数（截断前）
+	Results   []SearchResult    `json:"results"`          // 排序后的空教室列表
+	Failed    map[string]string `json:"failed,omitempty"` // 查询失败的教学楼及原因
+	FetchedAt time.Time         `json:"fetched_at"`       // 最早一个教学楼数据的获取时间
+	Stale     bool              `json:"stale"`            // 是否含有教务系统不可用时返回的历史数据
+
+	Recommend []Recommendation `json:"recommend,omitempty"` // 推荐的空教室，按得分从高到低
+}
+
+// Recommendation 推荐的空教室及推荐理由
+type Recommendation struct {
+	RoomName   string   `json:"room_name"`          // 教室名称
+	Building   string   `json:"building,omitempty"` // 所属教学楼
+	Score      float64  `json:"score"`              // 综合得分 (0-100)
+	FreeAfter  int      `json:"free_after"`         // 所选时间段之后连续空闲的节次组数
+	FreeUntil  string   `json:"free_until"`         // 连续空闲到的时间 (HH:MM)
+	BorrowRate *float64 `json:"borrow_rate"`        // 历史上该时段被借用或临时调课的比例，无历史数据时为 null
+	Seats      int      `json:"seats"`              // 座位数
+	Reasons    []string `json:"reasons"`            // 推荐理由
 }
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/cache"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/store"
+)
+
+// ErrNoSnapshotStore 没有配置快照存储，无法进行统计
+var ErrNoSnapshotStore = errors.New("未配置全天状态快照存储")
+
+// statsCacheTTL 占用统计结果的缓存时间
+// 统计需要读取全部快照文件，而快照每天只会增加几份，短时间内的结果不会有明显变化
+const statsCacheTTL = 10 * time.Minute
+
+// newStatsCache 创建占用统计结果的缓存
+func newStatsCache() *cache.TTLCache[string, *model.OccupancyResponse] {
+	return cache.NewTTLCache[string, *model.OccupancyResponse](statsCacheTTL, 0)
+}
+
+// occupancyCounter 累加占用统计
+type occupancyCounter struct {
+	model.Occupancy
+}
+
+// add 累加一个节次的状态，状态未知（0）的节次不计入
+func (c *occupancyCounter) add(statusID int) {
+	if statusID == 0 {
+		return
+	}
+	c.Observed++
+	if !model.IsFree(statusID) {
+		c.Occupied++
+	}
+	switch statusID {
+	case model.StatusExam:
+		c.Exam++
+	case model.StatusBorrowed:
+		c.Borrowed++
+	}
+}
+
+// result 计算占用率后返回统计结果
+func (c *occupancyCounter) result() model.Occupancy {
+	o := c.Occupancy
+	if o.Observed > 0 {
+		o.Rate = math.Round(float64(o.Occupied)/float64(o.Observed)*1000) / 1000
+	}
+	return o
+}
+
+// nodeCounters 按节次名称累加，保持节次首次出现的顺序
+type nodeCounters struct {
+	order  []model.NodeInfo
+	counts map[string]*occupancyCounter
+}
+
+func newNodeCounters() *nodeCounters {
+	return &nodeCounters{counts: make(map[string]*occupancyCounter)}
+}
+
+func (n *nodeCounters) get(node model.NodeInfo) *occupancyCounter {
+	c, ok := n.counts[node.NodeName]
+	if !ok {
+		c = &occupancyCounter{}
+		n.counts[node.NodeName] = c
+		n.order = append(n.order, node)
+	}
+	return c
+}
+
+func (n *nodeCounters) results() []model.NodeOccupancy {
+	nodes := make([]model.NodeOccupancy, 0, len(n.order))
+	for _, node := range n.order {
+		nodes = append(nodes, model.NodeOccupancy{
+			NodeName:  node.NodeName,
+			StartTime: node.StartTime,
+			EndTime:   node.EndTime,
+			Occupancy: n.counts[node.NodeName].result(),
+		})
+	}
+	return nodes
+}
+
+// OccupancyStats 汇总保存的全天状态快照，统计各教室、各节次和各星期几的占用情况
+// building 为空时统计该学期全部有快照的教学楼；fromWeek、toWeek 为 0 表示不限
+// 只读取本地快照，不访问教务系统；有快照时结果缓存 statsCacheTTL，返回值由调用方共享，不应修改
+func (s *ClassroomService) OccupancyStats(term, building string, fromWeek, toWeek int) (*model.OccupancyResponse, error) {
+	if s.store == nil {
+		return nil, ErrNoSnapshotStore
+	}
+	key := strings.Join([]string{term, building, fmt.Sprint(fromWeek), fmt.Sprint(toWeek)}, "|")
+	if cached, ok := s.statsCache.Get(key); ok {
+		return cached, nil
+	}
+
+	buildings := []string{building}
+	if building == "" {
+		var err error
+		if buildings, err = s.store.Buildings(term); err != nil {
+			return nil, fmt.Errorf("读取快照失败：%w", err)
+		}
+	}
+	var snaps []store.Snapshot
+	for _, b := range buildings {
+		loaded, err := s.store.Load(term, b, fromWeek, toWeek)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 的快照失败：%w", b, err)
+		}
+		snaps = append(snaps, loaded...)
+	}
+
+	var (
+		overall  occupancyCounter
+		rooms    = make(map[string]*occupancyCounter)
+		seats    = make(map[string]int)
+		nodes    = newNodeCounters()
+		weekdays = make(map[int]*nodeCounters)
+		dayCount = make(map[int]map[string]bool) // 星期 -> 日期集合
+		dates    = make(map[string]bool)
+		seen     = make(map[string]bool) // 教室|日期，教学楼名称模糊匹配时同一教室会出现在多个教学楼的快照中
+	)
+	for _, snap := range snaps {
+		dates[snap.Date] = true
+		if dayCount[snap.DayOfWeek] == nil {
+			dayCount[snap.DayOfWeek] = make(map[string]bool)
+			weekdays[snap.DayOfWeek] = newNodeCounters()
+		}
+		dayCount[snap.DayOfWeek][snap.Date] = true
+
+		for _, room := range snap.Classrooms {
+			key := room.RoomName + "|" + snap.Date
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if rooms[room.RoomName] == nil {
+				rooms[room.RoomName] = &occupancyCounter{}
+			}
+			if room.Seats > 0 {
+				seats[room.RoomName] = room.Seats
+			}
+			for i, node := range snap.NodeList {
+				if i >= len(room.Status) {
+					break
+				}
+				id := room.Status[i].StatusID
+				overall.add(id)
+				rooms[room.RoomName].add(id)
+				nodes.get(node).add(id)
+				weekdays[snap.DayOfWeek].get(node).add(id)
+			}
+		}
+	}
+
+	result := &model.OccupancyResponse{
+		Term:      term,
+		Building:  building,
+		StartWeek: fromWeek,
+		EndWeek:   toWeek,
+		Days:      len(snaps),
+		Dates:     make([]string, 0, len(dates)),
+		Overall:   overall.result(),
+		Rooms:     make([]model.RoomOccupancy, 0, len(rooms)),
+		Nodes:     nodes.results(),
+		Weekdays:  make([]model.WeekdayOccupancy, 0, len(weekdays)),
+	}
+	for date := range dates {
+		result.Dates = append(result.Dates, date)
+	}
+	sort.Strings(result.Dates)
+
+	for name, c := range rooms {
+		result.Rooms = append(result.Rooms, model.RoomOccupancy{RoomName: name, Seats: seats[name], Occupancy: c.result()})
+	}
+	sort.Slice(result.Rooms, func(i, j int) bool {
+		a, b := result.Rooms[i], result.Rooms[j]
+		if a.Rate != b.Rate {
+			return a.Rate > b.Rate
+		}
+		return a.RoomName < b.RoomName
+	})
+
+	for day := 1; day <= 7; day++ {
+		counters, ok := weekdays[day]
+		if !ok {
+			continue
+		}
+		wd := model.WeekdayOccupancy{DayOfWeek: day, Days: len(dayCount[day]), Nodes: counters.results()}
+		var total occupancyCounter
+		for i, node := range wd.Nodes {
+			total.Observed += node.Observed
+			total.Occupied += node.Occupied
+			if node.Observed == 0 {
+				continue
+			}
+			if wd.Busiest == nil || node.Rate > wd.Busiest.Rate {
+				wd.Busiest = &wd.Nodes[i]
+			}
+			if wd.Quietest == nil || node.Rate < wd.Quietest.Rate {
+				wd.Quietest = &wd.Nodes[i]
+			}
+		}
+		wd.Rate = total.result().Rate
+		result.Weekdays = append(result.Weekdays, wd)
+	}
+	// 还没有快照时不缓存，之后保存的第一份快照可以立即统计到
+	if len(snaps) > 0 {
+		s.statsCache.Set(key, result)
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/store"
+)
+
+const statsTerm = "2025-2026-1"
+
+// newStatsService 创建使用临时快照目录的服务，返回保存快照的函数
+func newStatsService(t *testing.T) (*ClassroomService, func(building, date string, week, day int, rooms map[string][]int)) {
+	t.Helper()
+	st, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := func(building, date string, week, day int, rooms map[string][]int) {
+		t.Helper()
+		snap := store.Snapshot{Term: statsTerm, Building: building, Date: date, Week: week, DayOfWeek: day, NodeList: scoreNodes[:3]}
+		for name, ids := range rooms {
+			snap.Classrooms = append(snap.Classrooms, model.ClassroomFullStatus{RoomName: name, Seats: 60, Status: scoreStatus(ids...)})
+		}
+		if err := st.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return newTestService(t, Config{}, nil, WithSnapshotStore(st)), save
+}
+
+func TestOccupancyStats(t *testing.T) {
+	s, save := newStatsService(t)
+	const exam, borrowed = model.StatusExam, model.StatusBorrowed
+	// 查询 "综合楼" 时模糊匹配带出了综合楼B201，与综合楼B 的快照重复
+	save("综合楼", "2026-03-02", 1, 1, map[string][]int{"综合楼101": {class, free, free}, "综合楼B201": {class, class, free}})
+	save("综合楼B", "2026-03-02", 1, 1, map[string][]int{"综合楼B201": {class, class, free}})
+	save("综合楼", "2026-03-09", 2, 1, map[string][]int{"综合楼101": {class, exam, free}})
+	save("综合楼", "2026-03-03", 1, 2, map[string][]int{"综合楼101": {free, free, borrowed}})
+
+	resp, err := s.OccupancyStats(statsTerm, "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Days != 4 || !slices.Equal(resp.Dates, []string{"2026-03-02", "2026-03-03", "2026-03-09"}) {
+		t.Errorf("Days, Dates = %d, %v", resp.Days, resp.Dates)
+	}
+	if want := (model.Occupancy{Observed: 12, Occupied: 6, Rate: 0.5, Exam: 1, Borrowed: 1}); resp.Overall != want {
+		t.Errorf("Overall = %+v，期望 %+v", resp.Overall, want)
+	}
+
+	// 同一教室同一天只统计一次，按占用率从高到低排列
+	wantRooms := []model.RoomOccupancy{
+		{RoomName: "综合楼B201", Seats: 60, Occupancy: model.Occupancy{Observed: 3, Occupied: 2, Rate: 0.667}},
+		{RoomName: "综合楼101", Seats: 60, Occupancy: model.Occupancy{Observed: 9, Occupied: 4, Rate: 0.444, Exam: 1, Borrowed: 1}},
+	}
+	if !slices.Equal(resp.Rooms, wantRooms) {
+		t.Errorf("Rooms = %+v，期望 %+v", resp.Rooms, wantRooms)
+	}
+
+	if len(resp.Weekdays) != 2 {
+		t.Fatalf("Weekdays = %+v，期望星期一、星期二两项", resp.Weekdays)
+	}
+	tests := []struct {
+		day               int
+		days              int
+		rate              float64
+		busiest, quietest string
+		rates             []float64
+	}{
+		{1, 2, 0.556, "0102", "0506", []float64{1, 0.667, 0}},
+		{2, 1, 0.333, "0506", "0102", []float64{0, 0, 1}},
+	}
+	for i, tt := range tests {
+		wd := resp.Weekdays[i]
+		if wd.DayOfWeek != tt.day || wd.Days != tt.days || wd.Rate != tt.rate {
+			t.Errorf("星期%d：DayOfWeek, Days, Rate = %d, %d, %v，期望 %d, %d, %v", tt.day, wd.DayOfWeek, wd.Days, wd.Rate, tt.day, tt.days, tt.rate)
+		}
+		if wd.Busiest == nil || wd.Busiest.NodeName != tt.busiest || wd.Quietest == nil || wd.Quietest.NodeName != tt.quietest {
+			t.Errorf("星期%d：最忙 %+v、最空闲 %+v，期望 %s、%s", tt.day, wd.Busiest, wd.Quietest, tt.busiest, tt.quietest)
+		}
+		var rates []float64
+		for _, node := range wd.Nodes {
+			rates = append(rates, node.Rate)
+		}
+		if !slices.Equal(rates, tt.rates) {
+			t.Errorf("星期%d 各节次占用率 = %v，期望 %v", tt.day, rates, tt.rates)
+		}
+	}
+
+	// 指定教学楼和周次
+	resp, err = s.OccupancyStats(statsTerm, "综合楼", 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Days != 1 || len(resp.Rooms) != 1 || resp.Rooms[0].Occupied != 2 {
+		t.Errorf("综合楼第 2 周：Days = %d，Rooms = %+v", resp.Days, resp.Rooms)
+	}
+}
+
+// TestOccupancyStatsEmptyNotCached 没有快照时的结果不缓存，保存快照后立即可以统计到
+func TestOccupancyStatsEmptyNotCached(t *testing.T) {
+	s, save := newStatsService(t)
+
+	resp, err := s.OccupancyStats(statsTerm, "综合楼", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Days != 0 || len(resp.Rooms) != 0 {
+		t.Fatalf("没有快照时 Days = %d，Rooms = %+v", resp.Days, resp.Rooms)
+	}
+
+	save("综合楼", "2026-03-02", 1, 1, map[string][]int{"综合楼101": {class, free, free}})
+	if resp, err = s.OccupancyStats(statsTerm, "综合楼", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Days != 1 {
+		t.Errorf("保存快照后 Days = %d，期望 1", resp.Days)
+	}
+
+	// 有快照的结果缓存，新保存的快照要等缓存过期
+	save("综合楼", "2026-03-03", 1, 2, map[string][]int{"综合楼101": {class, free, free}})
+	if resp, _ = s.OccupancyStats(statsTerm, "综合楼", 0, 0); resp.Days != 1 {
+		t.Errorf("缓存期间 Days = %d，期望仍为 1", resp.Days)
+	}
+}
+
+func TestOccupancyStatsNoStore(t *testing.T) {
+	s := newTestService(t, Config{}, nil)
+	if _, err := s.OccupancyStats(statsTerm, "", 0, 0); !errors.Is(err, ErrNoSnapshotStore) {
+		t.Errorf("没有快照存储时返回 %v，期望 ErrNoSnapshotStore", err)
+	}
+}
@@ -1,16 +1,19 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/metrics"
 	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
 	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
 	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
@@ -18,99 +21,87 @@ import (
 
 type CalendarService struct {
 	client         *cas.Client
-	currentYearStr string    // 学年学期 e.g. "2025-2026-1"
-	baseTime       time.Time // 获取周次的时间点
-	baseWeek       int       // 获取到的当前周次
-	hasPermission  bool      // 是否有权限访问
+	refreshTimeout time.Duration // 刷新日历（学期 + 周次两次请求）的总超时
+	currentYearStr string        // 学年学期 e.g. "2025-2026-1"
+	baseTime       time.Time     // 获取周次的时间点
+	baseWeek       int           // 获取到的当前周次
+	hasPermission  bool          // 是否有权限访问
 	mu             sync.RWMutex
 }
 
 var (
-	calendarInstance *CalendarService
+	// calendarInstance 由启动流程在后台写入，同时会被各请求读取，使用原子指针避免数据竞争
+	calendarInstance atomic.Pointer[CalendarService]
 	calendarOnce     sync.Once
 )
 
-// GetCalendarService 单例获取
+// GetCalendarService 单例获取，启动流程尚未创建时返回 nil
 func GetCalendarService() *CalendarService {
-	return calendarInstance
+	return calendarInstance.Load()
 }
 
 // InitCalendarService 初始化日历服务
-func InitCalendarService(client *cas.Client) error {
+func InitCalendarService(ctx context.Context, client *cas.Client, cfg Config) error {
 	var err error
 	calendarOnce.Do(func() {
-		calendarInstance = &CalendarService{
-			client: client,
+		cal := &CalendarService{
+			client:         client,
+			refreshTimeout: cfg.CalendarRefreshTimeout,
 		}
-		err = calendarInstance.Refresh()
+		calendarInstance.Store(cal)
+		err = cal.Refresh(ctx)
 	})
 	return err
 }
 
 // Refresh 从教务系统刷新当前周次信息
-func (s *CalendarService) Refresh() error {
+func (s *CalendarService) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.refreshTimeout)
+	defer cancel()
+
+	// 学期信息与周次信息互不依赖，并发获取
+	// 周次获取失败时取消学期查询，避免白白占用连接
+	var (
+		wg            sync.WaitGroup
+		termStr       string
+		hasPermission bool
+		termErr       error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		termStr, hasPermission, termErr = s.fetchTerm(ctx)
+	}()
+
+	htmlContent, err := s.fetchWeekPage(ctx)
+	// 无权限时按"非法访问"页面处理，与下方的内容检查走同一分支
+	weekNoPermission := errors.Is(err, cas.ErrNoPermission)
+	if err != nil && !weekNoPermission {
+		cancel()
+		wg.Wait()
+		return err
+	}
+	wg.Wait()
+
+	// 拿到全部结果后再加锁更新，避免请求期间阻塞读取方
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 尝试解析学期 (通常可以通过另一个接口获取，或者从其他页面获取)
-	// 这里为了简化，我们调用 jsjy_query 接口获取学年学期
-	// http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query
-	termUrl := "http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query"
-	termReq, _ := http.NewRequest("GET", termUrl, nil)
-	termResp, err := s.client.Do(termReq)
-	if err == nil {
-		defer termResp.Body.Close()
-
-		// 读取响应体内容以检查是否有权限
-		bodyBytes, _ := io.ReadAll(termResp.Body)
-		bodyString := string(bodyBytes)
-
-		// 检查是否包含 "非法访问"
-		if strings.Contains(bodyString, "非法访问") {
-			s.hasPermission = false
+	if termErr == nil {
+		if !hasPermission {
 			logger.Warn("警告：该账号无权限访问空教室查询接口 (jsjy_query)，请检查账号权限或登录状态。")
-		} else {
-			s.hasPermission = true
 		}
-
-		termDoc, _ := goquery.NewDocumentFromReader(strings.NewReader(bodyString))
-		// 查找包含学期的文本，例如 <td>学期：2025-2026-1 ...
-		// 简单粗暴正则匹配 d{4}-d{4}-\d
-		termText := termDoc.Text()
-		reTerm := regexp.MustCompile(`\d{4}-\d{4}-\d`)
-		termMatch := reTerm.FindString(termText)
-		if termMatch != "" {
-			s.currentYearStr = termMatch
+		s.hasPermission = hasPermission
+		if termStr != "" {
+			s.currentYearStr = termStr
 		}
 	} else {
 		// 如果请求失败，也认为是无权限的一种表现，或者是网络问题
 		// 默认设置为有权限，让用户去重试；或者根据错误类型判断
 		// 这里暂不修改 hasPermission，让后续逻辑处理
-		logger.Warn("警告：无法查询学期信息：%v", err)
+		logger.Warn("警告：无法查询学期信息：%v", termErr)
 	}
 
-	// 1. 获取教学周信息
-	// 接口：http://zhjw.qfnu.edu.cn/jsxsd/framework/jsMain_new.jsp?t1=1
-	// 响应示例：$("#li_showWeek").html("<span class=\"main_text main_color\">第18周</span>/20周");
-	url := "http://zhjw.qfnu.edu.cn/jsxsd/framework/jsMain_new.jsp?t1=1"
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("fetch calendar failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	htmlContent, _ := doc.Html()
-
 	// 解析周次
 	// 正则匹配：第(\d+)周
 	reWeek := regexp.MustCompile(`第(\d+)周`)
@@ -118,7 +109,7 @@ func (s *CalendarService) Refresh() error {
 	if len(matches) < 2 {
 		// 尝试匹配 "当前日期不在教学周历内"
 		// 增加对 "非法访问" 的检查，如果是非法访问，则不认为是解析失败，而是权限不足或Session过期
-		if strings.Contains(htmlContent, "非法访问") {
+		if weekNoPermission || strings.Contains(htmlContent, "非法访问") {
 			s.baseWeek = 0
 			// 只有在还没有被 jsjy_query 标记为无权限时才打印，避免重复
 			if s.hasPermission {
@@ -137,6 +128,7 @@ func (s *CalendarService) Refresh() error {
 				s.baseWeek = 0
 			} else {
 				// 真正无法解析的错误
+				metrics.ParseFailure("calendar_week")
 				return fmt.Errorf("无法从响应中解析周次信息，内容长度：%d", len(htmlContent))
 			}
 		}
@@ -152,10 +144,71 @@ func (s *CalendarService) Refresh() error {
 	}
 
 	s.baseTime = time.Now()
+	metrics.SetCalendar(s.currentYearStr, s.baseWeek)
 	logger.Info("日历已初始化：学期=%s，周次=%d，基准时间=%s", s.currentYearStr, s.baseWeek, s.baseTime.Format("2006-01-02"))
 	return nil
 }
 
+// fetchTerm 查询当前学年学期，同时判断账号是否有权限访问空教室查询接口
+func (s *CalendarService) fetchTerm(ctx context.Context) (term string, hasPermission bool, err error) {
+	// 尝试解析学期 (通常可以通过另一个接口获取，或者从其他页面获取)
+	// 这里为了简化，我们调用 jsjy_query 接口获取学年学期
+	// http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query
+	termUrl := "http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query"
+	termReq, err := http.NewRequestWithContext(ctx, "GET", termUrl, nil)
+	if err != nil {
+		return "", false, err
+	}
+	termResp, err := s.client.Do(termReq)
+	if errors.Is(err, cas.ErrNoPermission) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer termResp.Body.Close()
+
+	// 直接流式解析，不再额外复制一份响应体
+	termDoc, err := goquery.NewDocumentFromReader(termResp.Body)
+	if err != nil {
+		metrics.ParseFailure("calendar_term")
+		return "", false, err
+	}
+	// 查找包含学期的文本，例如 <td>学期：2025-2026-1 ...
+	// 简单粗暴正则匹配 d{4}-d{4}-\d
+	termText := termDoc.Text()
+
+	// 检查是否包含 "非法访问"，以判断是否有权限
+	hasPermission = !strings.Contains(termText, "非法访问")
+
+	reTerm := regexp.MustCompile(`\d{4}-\d{4}-\d`)
+	return reTerm.FindString(termText), hasPermission, nil
+}
+
+// fetchWeekPage 获取包含教学周信息的页面内容
+func (s *CalendarService) fetchWeekPage(ctx context.Context) (string, error) {
+	// 接口：教师端 jsMain_new.jsp?t1=1，学生端 xsMain_new.jsp?t1=1，按账号角色选择
+	// 响应示例：$("#li_showWeek").html("<span class=\"main_text main_color\">第18周</span>/20周");
+	url := s.client.WeekInfoURL()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch calendar failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return doc.Html()
+}
+
 // IsInTeachingCalendar 检查当前是否在教学周历内
 func (s *CalendarService) IsInTeachingCalendar() bool {
 	s.mu.RLock()
@@ -232,9 +285,33 @@ func (s *CalendarService) GetDateInfo(offset int) (info model.CalendarInfo, date
 	return
 }
 
+// LoadedAt 返回最近一次成功刷新日历的时间，从未成功时为零值
+func (s *CalendarService) LoadedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.baseTime
+}
+
 // HasPermission 返回是否有权限访问
 func (s *CalendarService) HasPermission() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.hasPermission
 }
+
+// DateOf 推算第 week 周星期 day（1-7）的日期
+// 以基准时间所在周为 baseWeek 推算，不在教学周历内时返回 false
+func (s *CalendarService) DateOf(week, day int) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.baseWeek <= 0 || s.baseTime.IsZero() {
+		return time.Time{}, false
+	}
+	baseWeekday := int(s.baseTime.Weekday())
+	if baseWeekday == 0 {
+		baseWeekday = 7
+	}
+	y, m, d := s.baseTime.Date()
+	monday := time.Date(y, m, d, 0, 0, 0, 0, s.baseTime.Location()).AddDate(0, 0, 1-baseWeekday)
+	return monday.AddDate(0, 0, (week-s.baseWeek)*7+day-1), true
+}
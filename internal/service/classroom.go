@@ -1,27 +1,105 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/cache"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/metrics"
 	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/store"
 	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+	"golang.org/x/sync/singleflight"
 )
 
 type ClassroomService struct {
 	client *cas.Client
+	cfg    Config
+
+	emptyCache   *cache.TTLCache[string, *model.ClassroomResponse]
+	fullDayCache *cache.TTLCache[string, *model.FullDayStatusResponse]
+	roomCache    *cache.TTLCache[string, *roomWeek]
+	roomFlight   singleflight.Group // 合并同一教室同一周并发的上游查询
+	historyCache *cache.TTLCache[string, map[string]borrowStat]
+	statsCache   *cache.TTLCache[string, *model.OccupancyResponse]
+
+	store *store.Store // 全天状态快照，为 nil 时不保存，推荐时不考虑历史借用情况
+}
+
+// Option ClassroomService 配置选项
+type Option func(*ClassroomService)
+
+// WithSnapshotStore 每次从教务系统获取全天状态后保存快照，并用历史快照辅助推荐
+func WithSnapshotStore(st *store.Store) Option {
+	return func(s *ClassroomService) {
+		s.store = st
+	}
+}
+
+func NewClassroomService(client *cas.Client, cfg Config, opts ...Option) *ClassroomService {
+	s := &ClassroomService{
+		client:       client,
+		cfg:          cfg,
+		emptyCache:   cache.NewTTLCache[string, *model.ClassroomResponse](cfg.CacheTTL, cfg.StaleRetain),
+		fullDayCache: cache.NewTTLCache[string, *model.FullDayStatusResponse](cfg.CacheTTL, cfg.StaleRetain),
+		roomCache:    cache.NewTTLCache[string, *roomWeek](cfg.CacheTTL, cfg.StaleRetain),
+		historyCache: newHistoryCache(),
+		statsCache:   newStatsCache(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// FlushCache 清空查询结果缓存，返回清除的条目数
+func (s *ClassroomService) FlushCache() int {
+	return s.emptyCache.Flush() + s.fullDayCache.Flush() + s.roomCache.Flush() + s.historyCache.Flush() + s.statsCache.Flush()
 }
 
-func NewClassroomService(client *cas.Client) *ClassroomService {
-	return &ClassroomService{client: client}
+// GetEmptyClassrooms 查询指定节次的空教室，并附上推荐的空教室
+func (s *ClassroomService) GetEmptyClassrooms(ctx context.Context, req model.QueryRequest) (*model.ClassroomResponse, error) {
+	resp, err := s.queryEmptyClassrooms(ctx, req)
+	if err != nil || s.cfg.RecommendCount <= 0 || len(resp.Classrooms) == 0 {
+		return resp, err
+	}
+
+	// 推荐需要全天状态判断之后的节次，与全天状态查询共用缓存；失败时只是不给出推荐
+	day, err := s.GetFullDayStatus(ctx, model.FullDayQueryRequest{BuildingName: req.BuildingName, DateOffset: req.DateOffset})
+	if err != nil {
+		logger.Warn("获取 %s 的全天状态失败，不提供推荐：%v", req.BuildingName, err)
+		return resp, nil
+	}
+	free := make(map[string]bool, len(resp.Classrooms))
+	for _, name := range resp.Classrooms {
+		free[name] = true
+	}
+	var candidates []model.ClassroomFullStatus
+	for _, room := range day.Classrooms {
+		if free[room.RoomName] {
+			candidates = append(candidates, room)
+		}
+	}
+	start, _ := strconv.Atoi(req.StartNode)
+	end, _ := strconv.Atoi(req.EndNode)
+
+	// 缓存中的结果是共享的，推荐写到副本上
+	result := *resp
+	result.Recommend = topRecommendations(s.recommendRooms(day, candidates, start, end, seatNeed{}), s.cfg.RecommendCount)
+	return &result, nil
 }
 
-func (s *ClassroomService) GetEmptyClassrooms(req model.QueryRequest) (*model.ClassroomResponse, error) {
+// queryEmptyClassrooms 从教务系统查询指定节次完全空闲的教室
+func (s *ClassroomService) queryEmptyClassrooms(ctx context.Context, req model.QueryRequest) (*model.ClassroomResponse, error) {
 	cal := GetCalendarService()
 	if cal == nil {
 		return nil, fmt.Errorf("日历服务未初始化")
@@ -30,6 +108,13 @@ func (s *ClassroomService) GetEmptyClassrooms(req model.QueryRequest) (*model.Cl
 	// 1. 获取日期和周次信息
 	calInfo, dateStr := cal.GetDateInfo(req.DateOffset)
 
+	cacheKey := strings.Join([]string{calInfo.Xnxqh, calInfo.Zc, calInfo.Xq, req.BuildingName, req.StartNode, req.EndNode}, "|")
+	if cached, ok := s.emptyCache.Get(cacheKey); ok {
+		metrics.CacheHit("empty")
+		return cached, nil
+	}
+	metrics.CacheMiss("empty")
+
 	// 2. 构建请求参数
 	// URL: http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query2
 	apiURL := "http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query2"
@@ -49,7 +134,9 @@ func (s *ClassroomService) GetEmptyClassrooms(req model.QueryRequest) (*model.Cl
 	params.Set("jc2", req.EndNode)
 
 	// 发送 POST 请求
-	httpReq, err := http.NewRequest("POST", apiURL, strings.NewReader(params.Encode()))
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.EmptyQueryTimeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(params.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +145,16 @@ func (s *ClassroomService) GetEmptyClassrooms(req model.QueryRequest) (*model.Cl
 
 	resp, err := s.client.Do(httpReq)
 	if err != nil {
+		// 教务系统熔断期间，返回最近一次成功查询的结果
+		if errors.Is(err, cas.ErrCircuitOpen) {
+			if stale, ok := s.emptyCache.GetStale(cacheKey); ok {
+				metrics.CacheStale("empty")
+				logger.Warn("教务系统熔断中，返回 %s 的缓存数据（获取于 %s）", req.BuildingName, stale.FetchedAt.Format("15:04:05"))
+				result := *stale
+				result.Stale = true
+				return &result, nil
+			}
+		}
 		return nil, fmt.Errorf("查询空教室失败：%w", err)
 	}
 	defer resp.Body.Close()
@@ -69,6 +166,7 @@ func (s *ClassroomService) GetEmptyClassrooms(req model.QueryRequest) (*model.Cl
 	// 3. 解析 HTML
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
 	if err != nil {
+		metrics.ParseFailure("empty_classrooms")
 		return nil, fmt.Errorf("解析 HTML 失败：%w", err)
 	}
 
@@ -104,16 +202,22 @@ func (s *ClassroomService) GetEmptyClassrooms(req model.QueryRequest) (*model.Cl
 	weekInt, _ := strconv.Atoi(calInfo.Zc)
 	dayInt, _ := strconv.Atoi(calInfo.Xq)
 
-	return &model.ClassroomResponse{
+	result := &model.ClassroomResponse{
 		Date:       dateStr,
 		Week:       weekInt,
 		DayOfWeek:  dayInt,
 		Classrooms: classrooms,
-	}, nil
+		FetchedAt:  time.Now(),
+	}
+	// 没有解析到教室时可能是页面结构变化或会话异常，不缓存，避免把错误结果当作兜底数据
+	if len(classrooms) > 0 {
+		s.emptyCache.Set(cacheKey, result)
+	}
+	return result, nil
 }
 
 // GetFullDayStatus 获取指定教学楼一整天的教室状态
-func (s *ClassroomService) GetFullDayStatus(req model.FullDayQueryRequest) (*model.FullDayStatusResponse, error) {
+func (s *ClassroomService) GetFullDayStatus(ctx context.Context, req model.FullDayQueryRequest) (*model.FullDayStatusResponse, error) {
 	cal := GetCalendarService()
 	if cal == nil {
 		return nil, fmt.Errorf("日历服务未初始化")
@@ -122,16 +226,33 @@ func (s *ClassroomService) GetFullDayStatus(req model.FullDayQueryRequest) (*mod
 	// 1. 获取日期和周次信息
 	calInfo, dateStr := cal.GetDateInfo(req.DateOffset)
 
+	cacheKey := strings.Join([]string{calInfo.Xnxqh, calInfo.Zc, calInfo.Xq, req.BuildingName}, "|")
+	if cached, ok := s.fullDayCache.Get(cacheKey); ok {
+		metrics.CacheHit("full_day")
+		return cached, nil
+	}
+	metrics.CacheMiss("full_day")
+
 	// 2. 一次查询全天所有节次（jc 和 jc2 置空）
-	nodeList, classrooms, err := s.queryFullDay(req.BuildingName, calInfo)
+	nodeList, classrooms, err := s.queryFullDay(ctx, req.BuildingName, calInfo)
 	if err != nil {
+		// 教务系统熔断期间，返回最近一次成功查询的结果
+		if errors.Is(err, cas.ErrCircuitOpen) {
+			if stale, ok := s.fullDayCache.GetStale(cacheKey); ok {
+				metrics.CacheStale("full_day")
+				logger.Warn("教务系统熔断中，返回 %s 的全天缓存数据（获取于 %s）", req.BuildingName, stale.FetchedAt.Format("15:04:05"))
+				result := *stale
+				result.Stale = true
+				return &result, nil
+			}
+		}
 		return nil, fmt.Errorf("查询全天状态失败：%w", err)
 	}
 
 	weekInt, _ := strconv.Atoi(calInfo.Zc)
 	dayInt, _ := strconv.Atoi(calInfo.Xq)
 
-	return &model.FullDayStatusResponse{
+	result := &model.FullDayStatusResponse{
 		Date:        dateStr,
 		Week:        weekInt,
 		DayOfWeek:   dayInt,
@@ -139,14 +260,76 @@ func (s *ClassroomService) GetFullDayStatus(req model.FullDayQueryRequest) (*mod
 		Building:    req.BuildingName,
 		NodeList:    nodeList,
 		Classrooms:  classrooms,
-	}, nil
+		FetchedAt:   time.Now(),
+	}
+	// 同上，节次或教室为空的结果不缓存，也不保存快照
+	if len(nodeList) > 0 && len(classrooms) > 0 {
+		s.fullDayCache.Set(cacheKey, result)
+		s.saveSnapshot(result)
+	}
+	return result, nil
+}
+
+// saveSnapshot 保存全天状态快照，失败只记录日志，不影响查询
+// 只保存配置中的教学楼，避免任意输入在快照目录中留下文件
+func (s *ClassroomService) saveSnapshot(resp *model.FullDayStatusResponse) {
+	if s.store == nil || len(resp.NodeList) == 0 {
+		return
+	}
+	building, rooms, ok := s.snapshotBuilding(resp)
+	if !ok {
+		return
+	}
+	snap := store.FromFullDay(resp)
+	snap.Building = building
+	snap.Classrooms = rooms
+	if err := s.store.Save(snap); err != nil {
+		logger.Warn("保存 %s %s 的全天状态快照失败：%v", building, resp.Date, err)
+	}
+}
+
+// snapshotBuilding 确定全天状态对应的规范教学楼名称，以及属于该教学楼的教室
+// 教学楼名称是模糊匹配，查询 "文史" 得到的是 "老文史楼" 的教室，也可能混入其他教学楼的教室；
+// 规范名称取配置中包含查询名称、且是返回的教室名称前缀的教学楼，匹配教室最多者优先
+// 没有匹配的配置教学楼时返回 false
+func (s *ClassroomService) snapshotBuilding(resp *model.FullDayStatusResponse) (string, []model.ClassroomFullStatus, bool) {
+	input := strings.TrimSpace(resp.Building)
+	if input == "" {
+		return "", nil, false
+	}
+	var best string
+	bestCount := 0
+	for _, c := range s.cfg.Campuses {
+		for _, b := range c.Buildings {
+			if !strings.Contains(b, input) {
+				continue
+			}
+			count := 0
+			for _, room := range resp.Classrooms {
+				if strings.HasPrefix(room.RoomName, b) {
+					count++
+				}
+			}
+			if count > bestCount {
+				best, bestCount = b, count
+			}
+		}
+	}
+	if best == "" {
+		return "", nil, false
+	}
+	rooms := make([]model.ClassroomFullStatus, 0, bestCount)
+	for _, room := range resp.Classrooms {
+		if strings.HasPrefix(room.RoomName, best) {
+			rooms = append(rooms, room)
+		}
+	}
+	return best, rooms, true
 }
 
 // queryFullDay 查询全天教室状态
 // 关键：jc 和 jc2 置空，同时不设置 jszt 参数，获取全天所有状态
-func (s *ClassroomService) queryFullDay(building string, calInfo model.CalendarInfo) ([]model.NodeInfo, []model.ClassroomFullStatus, error) {
-	apiURL := "http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query2"
-
+func (s *ClassroomService) queryFullDay(ctx context.Context, building string, calInfo model.CalendarInfo) ([]model.NodeInfo, []model.ClassroomFullStatus, error) {
 	params := url.Values{}
 	params.Set("typewhere", "jszq")
 	params.Set("xnxqh", calInfo.Xnxqh)
@@ -160,36 +343,65 @@ func (s *ClassroomService) queryFullDay(building string, calInfo model.CalendarI
 	// 关键：jc 和 jc2 置空，查询全天所有节次
 	// 不设置 jc 和 jc2 参数
 
-	httpReq, err := http.NewRequest("POST", apiURL, strings.NewReader(params.Encode()))
+	doc, err := s.postStatusQuery(ctx, params, "full_day")
 	if err != nil {
 		return nil, nil, err
 	}
+
+	nodeList, classrooms, err := parseFullDayStatusFromHTML(doc)
+	// 表头没有解析出节次，说明页面结构变了或返回的不是查询结果
+	if err == nil && len(nodeList) == 0 {
+		metrics.ParseFailure("full_day")
+		logger.Warn("全天状态页面未解析到节次信息：%s", building)
+	}
+	return nodeList, classrooms, err
+}
+
+// postStatusQuery 向 jsjy_query2 提交状态查询并解析返回的页面，kind 用于解析失败的指标
+func (s *ClassroomService) postStatusQuery(ctx context.Context, params url.Values, kind string) (*goquery.Document, error) {
+	apiURL := "http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query2"
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.FullDayQueryTimeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
 	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	httpReq.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
 	resp, err := s.client.Do(httpReq)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
 	if err != nil {
-		return nil, nil, err
+		metrics.ParseFailure(kind)
+		return nil, err
 	}
-
-	return parseFullDayStatusFromHTML(doc)
+	return doc, nil
 }
 
 // parseFullDayStatusFromHTML 从HTML中解析全天教室状态
 // 返回数据结构：按教室分组的节次状态列表（教室-节次-状态）
 func parseFullDayStatusFromHTML(doc *goquery.Document) ([]model.NodeInfo, []model.ClassroomFullStatus, error) {
+	nodeList, _, classrooms, err := parseStatusTable(doc)
+	return nodeList, classrooms, err
+}
+
+// parseStatusTable 解析教室状态表格
+// 查询多天（xq 与 xq2 不同）时表头按星期分组，days 给出每个节次列所属的星期（1-7），
+// 无法识别时为 0
+func parseStatusTable(doc *goquery.Document) (nodeList []model.NodeInfo, days []int, classrooms []model.ClassroomFullStatus, err error) {
 	// 解析表格结构：
-	// thead 中包含节次信息（第1节、第2节...）
+	// thead 第一行为星期（每个星期一个 th，colspan 为当天的节次列数）
+	// thead 第二行包含节次信息（第1节、第2节...）
 	// tbody 中每行代表一个教室，每列代表该教室在对应节次的状态
 
-	var nodeList []model.NodeInfo
 	classroomMap := make(map[string]*model.ClassroomFullStatus) // 临时map，key为教室名
+	var colDays []int                                           // 第一行展开 colspan 后每列对应的星期
 
 	// 先解析表头获取节次信息
 	// 修正：表头在 <thead id="thead1"> 中，第二行包含节次信息，且使用的是 td 标签而不是 th
@@ -202,6 +414,19 @@ func parseFullDayStatusFromHTML(doc *goquery.Document) ([]model.NodeInfo, []mode
 		// 我们只关心第二行（索引为 1），它包含具体的节次信息
 		// 注意：如果页面结构变化，这里可能需要调整
 		// 也可以通过判断内容来识别
+		if rowIdx == 0 {
+			tr.Find("th").Each(func(i int, th *goquery.Selection) {
+				if i == 0 {
+					return // "星期" 标题列
+				}
+				span, _ := strconv.Atoi(th.AttrOr("colspan", "1"))
+				day := parseWeekday(strings.TrimSpace(th.Text()))
+				for range max(span, 1) {
+					colDays = append(colDays, day)
+				}
+			})
+			return
+		}
 		if rowIdx != 1 {
 			return
 		}
@@ -230,10 +455,24 @@ func parseFullDayStatusFromHTML(doc *goquery.Document) ([]model.NodeInfo, []mode
 			// 所以 colIdx 直接对应即可
 			nodeColMap[colIdx] = nodeIdx
 
+			// 上下课时间，属性名经 HTML 解析后为小写；缺失时按作息时间表推算
+			start, _ := td.Attr("tdkssj")
+			end, _ := td.Attr("tdjssj")
+			if start == "" || end == "" {
+				start, end, _ = model.PeriodTimes(nodeName)
+			}
+
 			nodeList = append(nodeList, model.NodeInfo{
 				NodeIndex: nodeIdx + 1,
 				NodeName:  nodeName,
+				StartTime: start,
+				EndTime:   end,
 			})
+			day := 0
+			if nodeIdx < len(colDays) {
+				day = colDays[nodeIdx]
+			}
+			days = append(days, day)
 		})
 	})
 
@@ -243,11 +482,7 @@ func parseFullDayStatusFromHTML(doc *goquery.Document) ([]model.NodeInfo, []mode
 		firstTd := tr.Find("td").First()
 		text := strings.TrimSpace(firstTd.Text())
 
-		roomName := ""
-		idx := strings.Index(text, "(")
-		if idx > 0 {
-			roomName = strings.TrimSpace(text[:idx])
-		}
+		roomName, seats, examSeats := parseRoomLabel(text)
 		if roomName == "" {
 			return
 		}
@@ -255,8 +490,10 @@ func parseFullDayStatusFromHTML(doc *goquery.Document) ([]model.NodeInfo, []mode
 		// 初始化该教室的状态列表
 		if _, exists := classroomMap[roomName]; !exists {
 			classroomMap[roomName] = &model.ClassroomFullStatus{
-				RoomName: roomName,
-				Status:   make([]model.RoomStatus, len(nodeList)),
+				RoomName:  roomName,
+				Seats:     seats,
+				ExamSeats: examSeats,
+				Status:    make([]model.RoomStatus, len(nodeList)),
 			}
 		}
 
@@ -287,7 +524,6 @@ func parseFullDayStatusFromHTML(doc *goquery.Document) ([]model.NodeInfo, []mode
 	})
 
 	// 将 map 转换为切片
-	var classrooms []model.ClassroomFullStatus
 	for _, cs := range classroomMap {
 		classrooms = append(classrooms, *cs)
 	}
@@ -297,7 +533,37 @@ func parseFullDayStatusFromHTML(doc *goquery.Document) ([]model.NodeInfo, []mode
 		return classrooms[i].RoomName < classrooms[j].RoomName
 	})
 
-	return nodeList, classrooms, nil
+	return nodeList, days, classrooms, nil
+}
+
+// parseRoomLabel 解析教室单元格文本，如 "老文史楼101(75/30)"，括号内为座位数和考试座位数
+// 没有括号时返回空名称，与原先只取括号前名称的逻辑一致
+func parseRoomLabel(text string) (name string, seats, examSeats int) {
+	idx := strings.Index(text, "(")
+	if idx <= 0 {
+		return "", 0, 0
+	}
+	name = strings.TrimSpace(text[:idx])
+	inner := strings.TrimSuffix(strings.TrimSpace(text[idx+1:]), ")")
+	a, b, _ := strings.Cut(inner, "/")
+	seats, _ = strconv.Atoi(strings.TrimSpace(a))
+	examSeats, _ = strconv.Atoi(strings.TrimSpace(b))
+	return name, seats, examSeats
+}
+
+// parseWeekday 将 "星期一" ... "星期日" 转换为 1-7，无法识别时返回 0
+func parseWeekday(s string) int {
+	names := []string{"一", "二", "三", "四", "五", "六", "日"}
+	s = strings.TrimPrefix(s, "星期")
+	for i, name := range names {
+		if s == name {
+			return i + 1
+		}
+	}
+	if s == "天" {
+		return 7
+	}
+	return 0
 }
 
 // mapStatusCodeToID 将状态码映射到ID
@@ -322,6 +588,7 @@ func mapStatusCodeToID(code string) int {
 	case "M":
 		return 9
 	default:
+		metrics.UnknownStatusCode(code)
 		return 5 // 默认空闲
 	}
 }
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+)
+
+// roundTripFunc 用函数模拟教务系统
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// htmlResponse 构造状态码为 200 的 HTML 响应
+func htmlResponse(req *http.Request, body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// emptyListPage 构造空教室查询（jszt=8）返回的页面
+func emptyListPage(rooms ...string) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><table id="dataList"><tr><th>教室</th></tr>`)
+	for _, room := range rooms {
+		b.WriteString(`<tr><td><input type="checkbox"> ` + room + `(75/30)</td></tr>`)
+	}
+	b.WriteString(`</table></body></html>`)
+	return b.String()
+}
+
+// testRoom 状态表中的一行，cells 依次为各天各节次的状态符号，空字符串表示空闲
+type testRoom struct {
+	label string // 如 "老文史楼101(75/30)"
+	cells []string
+}
+
+// statusPage 构造 jsjy_query2 返回的状态表，days 为各天的星期（1-7），每天的节次为 nodes
+func statusPage(days []int, nodes []string, rooms ...testRoom) string {
+	names := []string{"一", "二", "三", "四", "五", "六", "日"}
+	var b strings.Builder
+	b.WriteString(`<html><body><table id="dataList"><thead id="thead1"><tr><th>星期</th>`)
+	for _, day := range days {
+		fmt.Fprintf(&b, `<th colspan="%d">星期%s</th>`, len(nodes), names[day-1])
+	}
+	b.WriteString(`</tr><tr><td></td>`)
+	for range days {
+		for _, node := range nodes {
+			fmt.Fprintf(&b, `<td tdvalue="%s">%s</td>`, node, node)
+		}
+	}
+	b.WriteString(`</tr></thead><tbody>`)
+	for _, room := range rooms {
+		b.WriteString(`<tr><td>` + room.label + `</td>`)
+		for _, cell := range room.cells {
+			b.WriteString(`<td>` + cell + `</td>`)
+		}
+		b.WriteString(`</tr>`)
+	}
+	b.WriteString(`</tbody></table></body></html>`)
+	return b.String()
+}
+
+// useTestCalendar 替换全局日历，当前为第 week 周，测试结束时恢复
+func useTestCalendar(t *testing.T, term string, week int) {
+	t.Helper()
+	prev := calendarInstance.Load()
+	calendarInstance.Store(&CalendarService{
+		currentYearStr: term,
+		baseTime:       time.Now(),
+		baseWeek:       week,
+		hasPermission:  true,
+	})
+	t.Cleanup(func() { calendarInstance.Store(prev) })
+}
+
+// newTestClient 创建通过 rt 访问教务系统的客户端
+func newTestClient(t *testing.T, rt roundTripFunc, opts ...cas.ClientOption) *cas.Client {
+	t.Helper()
+	client, err := cas.NewClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.GetClient().Transport = rt
+	return client
+}
+
+// newTestService 创建使用 client 的 ClassroomService，未设置的超时取 1 秒
+func newTestService(t *testing.T, cfg Config, client *cas.Client, opts ...Option) *ClassroomService {
+	t.Helper()
+	if cfg.EmptyQueryTimeout == 0 {
+		cfg.EmptyQueryTimeout = time.Second
+	}
+	if cfg.FullDayQueryTimeout == 0 {
+		cfg.FullDayQueryTimeout = time.Second
+	}
+	if cfg.RoomQueryTimeout == 0 {
+		cfg.RoomQueryTimeout = time.Second
+	}
+	return NewClassroomService(client, cfg, opts...)
+}
+
+// TestEmptyClassroomsStaleFallback 熔断期间返回过期的缓存结果并标记 Stale
+func TestEmptyClassroomsStaleFallback(t *testing.T) {
+	useTestCalendar(t, "2025-2026-1", 5)
+
+	tests := []struct {
+		name      string
+		rooms     []string
+		wantStale bool // 为 false 时熔断期间应返回 ErrCircuitOpen
+	}{
+		{"有缓存时返回过期结果", []string{"老文史楼101", "老文史楼102"}, true},
+		{"空结果不缓存", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := newTestClient(t, func(req *http.Request) (*http.Response, error) {
+				calls++
+				if calls == 1 {
+					return htmlResponse(req, emptyListPage(tt.rooms...)), nil
+				}
+				return nil, errors.New("connection refused")
+			}, cas.WithCircuitBreaker(cas.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}))
+			s := newTestService(t, Config{CacheTTL: time.Nanosecond, StaleRetain: time.Hour}, client)
+			req := model.QueryRequest{BuildingName: "老文史楼", StartNode: "01", EndNode: "02"}
+
+			// 第一次查询成功，缓存立即过期
+			first, err := s.GetEmptyClassrooms(context.Background(), req)
+			if err != nil {
+				t.Fatalf("第一次查询失败：%v", err)
+			}
+			if first.Stale || !slices.Equal(first.Classrooms, tt.rooms) {
+				t.Fatalf("第一次查询结果 = %+v", first)
+			}
+
+			// 第二次查询上游出错，触发熔断
+			if _, err := s.GetEmptyClassrooms(context.Background(), req); err == nil || errors.Is(err, cas.ErrCircuitOpen) {
+				t.Fatalf("上游出错时返回 %v，期望连接错误", err)
+			}
+
+			// 熔断期间不再请求上游
+			got, err := s.GetEmptyClassrooms(context.Background(), req)
+			if calls != 2 {
+				t.Errorf("上游收到 %d 个请求，熔断后不应再发送", calls)
+			}
+			if !tt.wantStale {
+				if !errors.Is(err, cas.ErrCircuitOpen) {
+					t.Errorf("没有缓存时返回 %v，期望 ErrCircuitOpen", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("熔断期间查询失败：%v", err)
+			}
+			if !got.Stale || !slices.Equal(got.Classrooms, tt.rooms) || !got.FetchedAt.Equal(first.FetchedAt) {
+				t.Errorf("熔断期间返回 %+v，期望第一次的结果并标记 Stale", got)
+			}
+			if first.Stale {
+				t.Error("返回过期结果时不应修改缓存中的条目")
+			}
+		})
+	}
+}
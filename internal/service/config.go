@@ -0,0 +1,21 @@
+package service
+
+import "time"
+
+// Config 查询服务的超时和缓存配置
+type Config struct {
+	EmptyQueryTimeout      time.Duration // 空教室查询（jsjy_query2，指定节次）
+	FullDayQueryTimeout    time.Duration // 全天状态查询（jsjy_query2，全部节次，页面较大）
+	RoomQueryTimeout       time.Duration // 单个教室多周状态查询的总超时，各周的请求共用
+	CalendarRefreshTimeout time.Duration // 刷新日历（学期 + 周次两次请求）的总超时
+	CacheTTL               time.Duration // 查询结果缓存时间
+	StaleRetain            time.Duration // 缓存过期后继续保留的时间，教务系统熔断时用作兜底数据
+	Campuses               []Campus      // 校区及其教学楼，跨教学楼搜索时使用
+	RecommendCount         int           // 查询结果中推荐的空教室数，0 表示不推荐（默认），开启后空教室查询会额外进行一次全天状态查询
+}
+
+// Campus 校区及其教学楼
+type Campus struct {
+	Name      string
+	Buildings []string
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/metrics"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// MaxRoomWeeks 单个教室一次最多查询的周数，每周需要一次上游请求
+const MaxRoomWeeks = 8
+
+// roomQueryConcurrency 查询单个教室多周状态时同时进行的上游请求数
+const roomQueryConcurrency = 2
+
+// ErrRoomNotFound 教务系统中没有该教室
+var ErrRoomNotFound = errors.New("未找到该教室")
+
+// roomWeek 单个教室一周的状态，按周缓存
+type roomWeek struct {
+	nodeList  []model.NodeInfo
+	week      model.RoomWeek
+	fetchedAt time.Time
+	stale     bool
+}
+
+// GetRoomSchedule 获取单个教室第 startWeek 至 endWeek 周每天各节次的状态
+// 教务系统在 zc 与 zc2 不同时会把多周的状态合并到一列，无法区分具体哪一周，
+// 因此按周分别查询（zc = zc2，xq 为 1-7），并发进行，每周的结果单独缓存；
+// 全部周次共用 RoomQueryTimeout 的总超时，避免一次请求长时间占用上游
+func (s *ClassroomService) GetRoomSchedule(ctx context.Context, room string, startWeek, endWeek int) (*model.RoomScheduleResponse, error) {
+	cal := GetCalendarService()
+	if cal == nil {
+		return nil, fmt.Errorf("日历服务未初始化")
+	}
+	room = strings.TrimSpace(room)
+	if room == "" {
+		return nil, fmt.Errorf("教室名称不能为空")
+	}
+	if startWeek < 1 || endWeek < startWeek {
+		return nil, fmt.Errorf("周次范围无效：%d-%d", startWeek, endWeek)
+	}
+	if n := endWeek - startWeek + 1; n > MaxRoomWeeks {
+		return nil, fmt.Errorf("一次最多查询 %d 周，当前为 %d 周", MaxRoomWeeks, n)
+	}
+	term := cal.GetCurrentYearStr()
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.RoomQueryTimeout)
+	defer cancel()
+
+	n := endWeek - startWeek + 1
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, roomQueryConcurrency)
+		results = make([]*roomWeek, n)
+		errs    = make([]error, n)
+	)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			results[i], errs[i] = s.queryRoomWeek(ctx, term, room, startWeek+i)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrRoomNotFound) {
+			return nil, fmt.Errorf("%w：%s", ErrRoomNotFound, room)
+		}
+		return nil, fmt.Errorf("查询 %s 第%d周状态失败：%w", room, startWeek+i, err)
+	}
+
+	result := &model.RoomScheduleResponse{
+		Room:        room,
+		CurrentTerm: term,
+		StartWeek:   startWeek,
+		EndWeek:     endWeek,
+		NodeList:    results[0].nodeList,
+		FetchedAt:   results[0].fetchedAt,
+		Free:        true,
+	}
+	for _, rw := range results {
+		// 缓存中的结果是共享的，日期写到副本上
+		week := model.RoomWeek{Week: rw.week.Week, Days: make([]model.RoomDay, len(rw.week.Days))}
+		for i, day := range rw.week.Days {
+			if date, ok := cal.DateOf(week.Week, day.DayOfWeek); ok {
+				day.Date = date.Format("2006-01-02")
+			}
+			week.Days[i] = day
+			for _, st := range day.Status {
+				result.Free = result.Free && model.IsFree(st.StatusID)
+			}
+		}
+		result.Weeks = append(result.Weeks, week)
+		if rw.fetchedAt.Before(result.FetchedAt) {
+			result.FetchedAt = rw.fetchedAt
+		}
+		result.Stale = result.Stale || rw.stale
+	}
+	return result, nil
+}
+
+// queryRoomWeek 查询单个教室一周七天的状态
+// 同一教室同一周同时只向上游发送一次请求，并发的查询（如多个订阅同一日历的客户端）共用结果；
+// 上游请求不随某个调用方取消，各调用方只在自己的 ctx 结束时放弃等待
+func (s *ClassroomService) queryRoomWeek(ctx context.Context, term, room string, week int) (*roomWeek, error) {
+	cacheKey := strings.Join([]string{term, strconv.Itoa(week), room}, "|")
+	if cached, ok := s.roomCache.Get(cacheKey); ok {
+		metrics.CacheHit("room")
+		return cached, nil
+	}
+	metrics.CacheMiss("room")
+
+	ch := s.roomFlight.DoChan(cacheKey, func() (any, error) {
+		return s.fetchRoomWeek(context.WithoutCancel(ctx), term, room, week, cacheKey)
+	})
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.(*roomWeek), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchRoomWeek 向教务系统查询单个教室一周七天的状态并缓存
+func (s *ClassroomService) fetchRoomWeek(ctx context.Context, term, room string, week int, cacheKey string) (*roomWeek, error) {
+	zc := strconv.Itoa(week)
+	params := url.Values{}
+	params.Set("typewhere", "jszq")
+	params.Set("xnxqh", term)
+	params.Set("jsmc_mh", room) // 模糊匹配，结果中还需按教室名精确筛选
+	params.Set("bjfh", "=")
+	params.Set("zc", zc)
+	params.Set("zc2", zc)
+	params.Set("xq", "1")
+	params.Set("xq2", "7")
+
+	doc, err := s.postStatusQuery(ctx, params, "room")
+	if err != nil {
+		// 教务系统熔断期间，返回最近一次成功查询的结果
+		if errors.Is(err, cas.ErrCircuitOpen) {
+			if stale, ok := s.roomCache.GetStale(cacheKey); ok {
+				metrics.CacheStale("room")
+				logger.Warn("教务系统熔断中，返回 %s 第%d周的缓存数据（获取于 %s）", room, week, stale.fetchedAt.Format("15:04:05"))
+				result := *stale
+				result.stale = true
+				return &result, nil
+			}
+		}
+		return nil, err
+	}
+
+	nodeList, days, classrooms, err := parseStatusTable(doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeList) == 0 {
+		metrics.ParseFailure("room")
+		logger.Warn("教室状态页面未解析到节次信息：%s 第%d周", room, week)
+		return nil, fmt.Errorf("未解析到节次信息")
+	}
+
+	var status []model.RoomStatus
+	found := false
+	for _, c := range classrooms {
+		if c.RoomName == room {
+			status, found = c.Status, true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrRoomNotFound
+	}
+
+	result := splitRoomWeek(nodeList, days, status)
+	if len(result.week.Days) == 0 {
+		metrics.ParseFailure("room")
+		logger.Warn("教室状态页面未解析到星期信息：%s 第%d周", room, week)
+		return nil, fmt.Errorf("未解析到星期信息")
+	}
+	result.week.Week = week
+	result.fetchedAt = time.Now()
+	s.roomCache.Set(cacheKey, result)
+	return result, nil
+}
+
+// splitRoomWeek 将多天查询结果的节次列按星期拆分，每天的节次索引从 1 开始
+// 节次列表取第一天的表头，教务系统每天的节次划分相同
+func splitRoomWeek(nodeList []model.NodeInfo, days []int, status []model.RoomStatus) *roomWeek {
+	result := &roomWeek{}
+	byDay := make(map[int][]int) // 星期 -> 节次列下标
+	var order []int
+	for col, day := range days {
+		if day == 0 {
+			continue
+		}
+		if _, ok := byDay[day]; !ok {
+			order = append(order, day)
+		}
+		byDay[day] = append(byDay[day], col)
+	}
+
+	for _, day := range order {
+		cols := byDay[day]
+		if result.nodeList == nil {
+			for i, col := range cols {
+				node := nodeList[col]
+				node.NodeIndex = i + 1
+				result.nodeList = append(result.nodeList, node)
+			}
+		}
+		rd := model.RoomDay{DayOfWeek: day, Status: make([]model.RoomStatus, len(cols))}
+		for i, col := range cols {
+			var st model.RoomStatus
+			if col < len(status) {
+				st = status[col]
+			}
+			st.NodeIndex = i + 1
+			rd.Status[i] = st
+		}
+		result.week.Days = append(result.week.Days, rd)
+	}
+	return result
+}
+
+// RoomFilter 单个教室查询结果的筛选条件，零值表示不筛选
+type RoomFilter struct {
+	FromDay   int // 起始星期 (1-7)
+	ToDay     int // 终止星期 (1-7)
+	StartNode int // 起始节次 (1-11)
+	EndNode   int // 终止节次 (1-11)
+}
+
+// FilterRoomSchedule 按星期和节次筛选单个教室的查询结果，返回新的结果
+// 节次组（如 "0506"）与 [StartNode, EndNode] 有重叠即保留，并重新计算 Free
+func FilterRoomSchedule(resp *model.RoomScheduleResponse, f RoomFilter) *model.RoomScheduleResponse {
+	var keep []int
+	for i, node := range resp.NodeList {
+		if nodeOverlaps(node.NodeName, f.StartNode, f.EndNode) {
+			keep = append(keep, i)
+		}
+	}
+
+	result := *resp
+	result.NodeList = make([]model.NodeInfo, 0, len(keep))
+	for i, idx := range keep {
+		node := resp.NodeList[idx]
+		node.NodeIndex = i + 1
+		result.NodeList = append(result.NodeList, node)
+	}
+
+	result.Free = true
+	result.Weeks = make([]model.RoomWeek, 0, len(resp.Weeks))
+	for _, week := range resp.Weeks {
+		w := model.RoomWeek{Week: week.Week}
+		for _, day := range week.Days {
+			if (f.FromDay > 0 && day.DayOfWeek < f.FromDay) || (f.ToDay > 0 && day.DayOfWeek > f.ToDay) {
+				continue
+			}
+			d := model.RoomDay{DayOfWeek: day.DayOfWeek, Date: day.Date, Status: make([]model.RoomStatus, 0, len(keep))}
+			for i, idx := range keep {
+				var st model.RoomStatus
+				if idx < len(day.Status) {
+					st = day.Status[idx]
+				}
+				st.NodeIndex = i + 1
+				d.Status = append(d.Status, st)
+				result.Free = result.Free && model.IsFree(st.StatusID)
+			}
+			w.Days = append(w.Days, d)
+		}
+		result.Weeks = append(result.Weeks, w)
+	}
+	return &result
+}
+
+// nodeOverlaps 判断节次组（如 "0506"、"091011"）是否与 [start, end] 有重叠，start/end 为 0 表示不限
+func nodeOverlaps(group string, start, end int) bool {
+	if start == 0 && end == 0 {
+		return true
+	}
+	if len(group) < 2 || len(group)%2 != 0 {
+		return false
+	}
+	first, err1 := strconv.Atoi(group[:2])
+	last, err2 := strconv.Atoi(group[len(group)-2:])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	if start > 0 && last < start {
+		return false
+	}
+	if end > 0 && first > end {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+)
+
+// formOf 读取发往教务系统的表单参数
+func formOf(t *testing.T, req *http.Request) url.Values {
+	t.Helper()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Error(err)
+	}
+	return form
+}
+
+// roomWeekPage 格物楼B203 在第 week 周星期 week%7+1 的第一个节次上课，其余空闲；
+// 模糊匹配还会带出格物楼B2031，它每个节次都在上课
+func roomWeekPage(week int) string {
+	days := []int{1, 2, 3, 4, 5, 6, 7}
+	nodes := []string{"0102", "0304"}
+	target := make([]string, len(days)*len(nodes))
+	target[(week%7)*len(nodes)] = "◆"
+	other := make([]string, len(target))
+	for i := range other {
+		other[i] = "◆"
+	}
+	return statusPage(days, nodes,
+		testRoom{"格物楼B203(60/30)", target},
+		testRoom{"格物楼B2031(50/20)", other},
+	)
+}
+
+// TestRoomScheduleSharesQueries 并发查询同一教室时，每周只向教务系统请求一次
+func TestRoomScheduleSharesQueries(t *testing.T) {
+	useTestCalendar(t, "2025-2026-1", 5)
+
+	var (
+		mu      sync.Mutex
+		perWeek = make(map[string]int)
+		release = make(chan struct{})
+	)
+	s := newTestService(t, Config{CacheTTL: time.Minute}, newTestClient(t, func(req *http.Request) (*http.Response, error) {
+		form := formOf(t, req)
+		if form.Get("zc") != form.Get("zc2") || form.Get("xq") != "1" || form.Get("xq2") != "7" {
+			t.Errorf("查询参数 = %v，期望按周查询七天", form)
+		}
+		mu.Lock()
+		perWeek[form.Get("zc")]++
+		mu.Unlock()
+		<-release
+		week, _ := strconv.Atoi(form.Get("zc"))
+		return htmlResponse(req, roomWeekPage(week)), nil
+	}))
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([]*model.RoomScheduleResponse, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.GetRoomSchedule(context.Background(), "格物楼B203", 5, 6)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for week, n := range perWeek {
+		if n != 1 {
+			t.Errorf("第%s周请求了 %d 次，期望 1 次", week, n)
+		}
+	}
+	if len(perWeek) != 2 {
+		t.Errorf("请求的周次 = %v，期望第 5、6 周", perWeek)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("第 %d 个查询失败：%v", i+1, err)
+		}
+	}
+
+	resp := results[0]
+	if len(resp.Weeks) != 2 || len(resp.NodeList) != 2 || resp.Free {
+		t.Fatalf("查询结果 = %+v", resp)
+	}
+	for _, week := range resp.Weeks {
+		if len(week.Days) != 7 {
+			t.Fatalf("第%d周有 %d 天，期望 7 天", week.Week, len(week.Days))
+		}
+		for _, day := range week.Days {
+			if day.Date == "" {
+				t.Errorf("第%d周星期%d 没有日期", week.Week, day.DayOfWeek)
+			}
+			// 只取名称完全一致的教室，不受模糊匹配带出的格物楼B2031 影响
+			want := model.StatusFree
+			if day.DayOfWeek == week.Week%7+1 {
+				want = model.StatusClass
+			}
+			if got := day.Status[0].StatusID; got != want {
+				t.Errorf("第%d周星期%d 第一个节次状态 = %d，期望 %d", week.Week, day.DayOfWeek, got, want)
+			}
+		}
+	}
+}
+
+func TestRoomScheduleWeekLimit(t *testing.T) {
+	useTestCalendar(t, "2025-2026-1", 5)
+	s := newTestService(t, Config{}, newTestClient(t, func(req *http.Request) (*http.Response, error) {
+		t.Error("超出周数上限时不应请求教务系统")
+		return nil, io.EOF
+	}))
+	_, err := s.GetRoomSchedule(context.Background(), "格物楼B203", 1, MaxRoomWeeks+1)
+	if err == nil || !strings.Contains(err.Error(), "最多查询") {
+		t.Errorf("超出周数上限时返回 %v", err)
+	}
+}
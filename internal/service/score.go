@@ -0,0 +1,214 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/cache"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// 各评分项的权重，某项不适用时（没有历史数据、没有座位需求）按其余各项的权重归一化
+const (
+	weightFreeAfter = 0.4  // 所选时间段之后还能继续空闲多久
+	weightBorrow    = 0.35 // 历史上该时段被借用或临时调课的可能性
+	weightCapacity  = 0.25 // 座位数与需求的匹配程度
+)
+
+// historyCacheTTL 历史借用统计的缓存时间，快照每天只会增加几份，不需要频繁重新读取
+const historyCacheTTL = 10 * time.Minute
+
+// seatNeed 座位数需求，0 表示不限
+type seatNeed struct {
+	min, max int
+}
+
+// borrowStat 某教室在历史快照中的借用情况
+type borrowStat struct {
+	days     int // 有记录的天数
+	borrowed int // 所选时间段内出现借用（Ｊ）或临时调课（Ｌ）的天数
+}
+
+// newHistoryCache 创建历史借用统计的缓存
+func newHistoryCache() *cache.TTLCache[string, map[string]borrowStat] {
+	return cache.NewTTLCache[string, map[string]borrowStat](historyCacheTTL, 0)
+}
+
+// recommendRooms 对某教学楼当天的候选空教室评分
+// start/end 为所选节次范围，历史借用情况取同一学期、同一星期且早于当天的快照
+func (s *ClassroomService) recommendRooms(day *model.FullDayStatusResponse, rooms []model.ClassroomFullStatus, start, end int, need seatNeed) []model.Recommendation {
+	history := s.borrowHistory(day, start, end)
+	recs := make([]model.Recommendation, 0, len(rooms))
+	for _, room := range rooms {
+		var hist *borrowStat
+		if st, ok := history[room.RoomName]; ok && st.days > 0 {
+			hist = &st
+		}
+		rec := scoreRoom(day.NodeList, room, start, end, need, hist, day.DayOfWeek)
+		rec.Building = day.Building
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+// topRecommendations 按得分从高到低排序，返回前 n 个
+func topRecommendations(recs []model.Recommendation, n int) []model.Recommendation {
+	sort.SliceStable(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].RoomName < recs[j].RoomName
+	})
+	if len(recs) > n {
+		recs = recs[:n]
+	}
+	return recs
+}
+
+// scoreRoom 计算单个教室的得分和推荐理由
+func scoreRoom(nodes []model.NodeInfo, room model.ClassroomFullStatus, start, end int, need seatNeed, hist *borrowStat, weekday int) model.Recommendation {
+	rec := model.Recommendation{RoomName: room.RoomName, Seats: room.Seats}
+	var total, weights float64
+
+	// 1. 之后连续空闲的节次组
+	last := -1
+	for i, node := range nodes {
+		if nodeOverlaps(node.NodeName, start, end) {
+			last = i
+		}
+	}
+	if last >= 0 {
+		rec.FreeUntil = nodes[last].EndTime
+		remaining := len(nodes) - last - 1
+		for i := last + 1; i < len(nodes) && i < len(room.Status); i++ {
+			if !model.IsFree(room.Status[i].StatusID) {
+				break
+			}
+			rec.FreeAfter++
+			rec.FreeUntil = nodes[i].EndTime
+		}
+		factor := 1.0
+		switch {
+		case remaining == 0:
+			rec.Reasons = append(rec.Reasons, "所选时间段已是当天最后的节次")
+		case rec.FreeAfter > 0:
+			factor = float64(rec.FreeAfter) / float64(remaining)
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("之后连续空闲 %d 个节次，可以用到 %s", rec.FreeAfter, rec.FreeUntil))
+		default:
+			factor = 0
+			next := last + 1
+			if next < len(room.Status) {
+				rec.Reasons = append(rec.Reasons, fmt.Sprintf("之后紧接着是%s（%s）", model.StatusName(room.Status[next].StatusID), nodes[next].StartTime))
+			}
+		}
+		total += weightFreeAfter * factor
+		weights += weightFreeAfter
+	}
+
+	// 2. 历史借用情况
+	if hist != nil {
+		rate := float64(hist.borrowed) / float64(hist.days)
+		rec.BorrowRate = &rate
+		total += weightBorrow * (1 - rate)
+		weights += weightBorrow
+		if hist.borrowed == 0 {
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("过去 %d 个%s该时段从未被借用或临时调课", hist.days, weekdayLabel(weekday)))
+		} else {
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("过去 %d 个%s中有 %d 次该时段被借用或临时调课", hist.days, weekdayLabel(weekday), hist.borrowed))
+		}
+	}
+
+	// 3. 座位数匹配程度：满足需求且不超过需求的两倍时得满分，越大越低
+	if need.min > 0 || need.max > 0 {
+		factor := 1.0
+		switch {
+		case room.Seats == 0:
+			factor = 0.5 // 未知座位数
+		case need.min > 0 && room.Seats < need.min, need.max > 0 && room.Seats > need.max:
+			factor = 0
+		case need.min > 0 && room.Seats > 2*need.min:
+			factor = float64(2*need.min) / float64(room.Seats)
+		}
+		total += weightCapacity * factor
+		weights += weightCapacity
+		if room.Seats > 0 && factor > 0 {
+			rec.Reasons = append(rec.Reasons, seatReason(room.Seats, need))
+		}
+	} else if room.Seats > 0 {
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("座位数 %d", room.Seats))
+	}
+
+	if weights > 0 {
+		rec.Score = math.Round(total/weights*1000) / 10
+	}
+	return rec
+}
+
+// seatReason 座位数相关的推荐理由
+func seatReason(seats int, need seatNeed) string {
+	switch {
+	case need.min > 0 && need.max > 0:
+		return fmt.Sprintf("座位数 %d，在 %d-%d 座的需求范围内", seats, need.min, need.max)
+	case need.min > 0:
+		return fmt.Sprintf("座位数 %d，满足至少 %d 座的需求", seats, need.min)
+	}
+	return fmt.Sprintf("座位数 %d，不超过 %d 座", seats, need.max)
+}
+
+// weekdayLabel 星期的中文名称，如 "星期四"
+func weekdayLabel(day int) string {
+	names := []string{"一", "二", "三", "四", "五", "六", "日"}
+	if day < 1 || day > len(names) {
+		return "同一天"
+	}
+	return "星期" + names[day-1]
+}
+
+// borrowHistory 统计教学楼各教室在历史快照中同一星期、所选时间段的借用情况
+// 没有配置快照存储、教学楼不在配置中或读取失败时返回空结果，评分时忽略该项
+func (s *ClassroomService) borrowHistory(day *model.FullDayStatusResponse, start, end int) map[string]borrowStat {
+	if s.store == nil {
+		return nil
+	}
+	building, _, ok := s.snapshotBuilding(day)
+	if !ok {
+		return nil
+	}
+	key := strings.Join([]string{day.CurrentTerm, building, day.Date, fmt.Sprint(start), fmt.Sprint(end)}, "|")
+	if cached, ok := s.historyCache.Get(key); ok {
+		return cached
+	}
+
+	snaps, err := s.store.Load(day.CurrentTerm, building, 0, 0)
+	if err != nil {
+		logger.Warn("读取 %s 的历史快照失败：%v", building, err)
+		return nil
+	}
+	stats := make(map[string]borrowStat)
+	for _, snap := range snaps {
+		// 只统计早于所查日期的快照，当天及之后的快照对所查日期而言不是历史
+		if snap.DayOfWeek != day.DayOfWeek || snap.Date >= day.Date {
+			continue
+		}
+		for _, room := range snap.Classrooms {
+			st := stats[room.RoomName]
+			st.days++
+			for i, node := range snap.NodeList {
+				if i >= len(room.Status) || !nodeOverlaps(node.NodeName, start, end) {
+					continue
+				}
+				if id := room.Status[i].StatusID; id == model.StatusBorrowed || id == model.StatusTempAdjust {
+					st.borrowed++
+					break
+				}
+			}
+			stats[room.RoomName] = st
+		}
+	}
+	s.historyCache.Set(key, stats)
+	return stats
+}
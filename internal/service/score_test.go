@@ -0,0 +1,179 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/store"
+)
+
+var scoreNodes = []model.NodeInfo{
+	{NodeIndex: 1, NodeName: "0102", StartTime: "08:00", EndTime: "09:50"},
+	{NodeIndex: 2, NodeName: "0304", StartTime: "10:10", EndTime: "12:00"},
+	{NodeIndex: 3, NodeName: "0506", StartTime: "14:00", EndTime: "15:50"},
+	{NodeIndex: 4, NodeName: "0708", StartTime: "16:10", EndTime: "18:00"},
+	{NodeIndex: 5, NodeName: "0910", StartTime: "19:00", EndTime: "20:50"},
+}
+
+// scoreStatus 按状态 ID 构造各节次的状态
+func scoreStatus(ids ...int) []model.RoomStatus {
+	status := make([]model.RoomStatus, len(ids))
+	for i, id := range ids {
+		status[i] = model.RoomStatus{NodeIndex: i + 1, StatusID: id}
+	}
+	return status
+}
+
+const (
+	free  = model.StatusFree
+	class = model.StatusClass
+)
+
+func TestScoreRoom(t *testing.T) {
+	tests := []struct {
+		name          string
+		seats         int
+		status        []model.RoomStatus
+		start, end    int
+		need          seatNeed
+		hist          *borrowStat
+		wantScore     float64
+		wantFreeAfter int
+		wantUntil     string
+		wantRate      float64 // 小于 0 表示没有历史数据
+		wantReason    string
+	}{
+		{
+			name:   "之后全部空闲",
+			status: scoreStatus(class, free, free, free, free), start: 3, end: 4,
+			wantScore: 100, wantFreeAfter: 3, wantUntil: "20:50", wantRate: -1,
+			wantReason: "之后连续空闲 3 个节次，可以用到 20:50",
+		},
+		{
+			name:   "之后部分空闲",
+			status: scoreStatus(free, free, free, class, free), start: 3, end: 4,
+			wantScore: 33.3, wantFreeAfter: 1, wantUntil: "15:50", wantRate: -1,
+		},
+		{
+			name:   "之后紧接着上课",
+			status: scoreStatus(free, free, class, free, free), start: 3, end: 4,
+			wantScore: 0, wantUntil: "12:00", wantRate: -1,
+			wantReason: "之后紧接着是正常上课（14:00）",
+		},
+		{
+			name:   "当天最后的节次",
+			status: scoreStatus(class, class, class, class, free), start: 9, end: 10,
+			wantScore: 100, wantUntil: "20:50", wantRate: -1,
+			wantReason: "所选时间段已是当天最后的节次",
+		},
+		{
+			name:   "参考历史借用情况",
+			status: scoreStatus(free, free, free, free, free), start: 3, end: 4,
+			hist:      &borrowStat{days: 4, borrowed: 1},
+			wantScore: 88.3, wantFreeAfter: 3, wantUntil: "20:50", wantRate: 0.25,
+			wantReason: "过去 4 个星期四中有 1 次该时段被借用或临时调课",
+		},
+		{
+			name:   "座位远多于需求",
+			seats:  200,
+			status: scoreStatus(free, free, free, free, free), start: 3, end: 4,
+			need:      seatNeed{min: 50},
+			wantScore: 80.8, wantFreeAfter: 3, wantUntil: "20:50", wantRate: -1,
+			wantReason: "座位数 200，满足至少 50 座的需求",
+		},
+		{
+			name:   "座位不足",
+			seats:  40,
+			status: scoreStatus(free, free, free, free, free), start: 3, end: 4,
+			need:      seatNeed{min: 50},
+			wantScore: 61.5, wantFreeAfter: 3, wantUntil: "20:50", wantRate: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := model.ClassroomFullStatus{RoomName: "老文史楼101", Seats: tt.seats, Status: tt.status}
+			rec := scoreRoom(scoreNodes, room, tt.start, tt.end, tt.need, tt.hist, 4)
+			if math.Abs(rec.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("Score = %v，期望 %v", rec.Score, tt.wantScore)
+			}
+			if rec.FreeAfter != tt.wantFreeAfter || rec.FreeUntil != tt.wantUntil {
+				t.Errorf("FreeAfter, FreeUntil = %d, %q，期望 %d, %q", rec.FreeAfter, rec.FreeUntil, tt.wantFreeAfter, tt.wantUntil)
+			}
+			switch {
+			case tt.wantRate < 0 && rec.BorrowRate != nil:
+				t.Errorf("没有历史数据时 BorrowRate = %v，期望 nil", *rec.BorrowRate)
+			case tt.wantRate >= 0 && (rec.BorrowRate == nil || *rec.BorrowRate != tt.wantRate):
+				t.Errorf("BorrowRate = %v，期望 %v", rec.BorrowRate, tt.wantRate)
+			}
+			if tt.wantReason != "" && !strings.Contains(strings.Join(rec.Reasons, "；"), tt.wantReason) {
+				t.Errorf("推荐理由 %q 中没有 %q", rec.Reasons, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestTopRecommendations(t *testing.T) {
+	recs := []model.Recommendation{
+		{RoomName: "B", Score: 50},
+		{RoomName: "C", Score: 90},
+		{RoomName: "A", Score: 50},
+	}
+	got := topRecommendations(recs, 2)
+	if len(got) != 2 || got[0].RoomName != "C" || got[1].RoomName != "A" {
+		t.Errorf("推荐结果 = %+v，期望 C、A（同分按名称）", got)
+	}
+}
+
+// TestBorrowHistory 借用历史只统计同一星期、早于所查日期的快照
+func TestBorrowHistory(t *testing.T) {
+	st, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(t, Config{Campuses: []Campus{{Name: "曲阜校区", Buildings: []string{"老文史楼"}}}}, nil, WithSnapshotStore(st))
+
+	save := func(date string, week, day int, status ...int) {
+		t.Helper()
+		err := st.Save(store.Snapshot{
+			Term: "2025-2026-2", Building: "老文史楼", Date: date, Week: week, DayOfWeek: day,
+			NodeList: scoreNodes[:len(status)],
+			Classrooms: []model.ClassroomFullStatus{
+				{RoomName: "老文史楼101", Status: scoreStatus(status...)},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	const borrowed, adjusted = model.StatusBorrowed, model.StatusTempAdjust
+	save("2026-03-05", 1, 4, free, borrowed, free) // 之前的星期四，所选时段借用
+	save("2026-03-12", 2, 4, borrowed, free, free) // 之前的星期四，只在其他时段借用
+	save("2026-03-10", 2, 2, free, borrowed, free) // 星期二，不统计
+	save("2026-03-19", 3, 4, free, adjusted, free) // 所查当天，不统计
+	save("2026-03-26", 4, 4, free, borrowed, free) // 之后的星期四，不统计
+
+	day := &model.FullDayStatusResponse{
+		CurrentTerm: "2025-2026-2",
+		Building:    "老文史楼",
+		Date:        "2026-03-19",
+		DayOfWeek:   4,
+		Classrooms:  []model.ClassroomFullStatus{{RoomName: "老文史楼101"}},
+	}
+	history := s.borrowHistory(day, 3, 4)
+	if got, want := history["老文史楼101"], (borrowStat{days: 2, borrowed: 1}); got != want {
+		t.Errorf("借用统计 = %+v，期望 %+v", got, want)
+	}
+
+	// 查询过去的日期时，之后保存的快照同样不算
+	day.Date = "2026-03-12"
+	if got, want := s.borrowHistory(day, 3, 4)["老文史楼101"], (borrowStat{days: 1, borrowed: 1}); got != want {
+		t.Errorf("查询 %s 时借用统计 = %+v，期望 %+v", day.Date, got, want)
+	}
+
+	// 没有开启快照存储时不参考历史
+	if got := newTestService(t, Config{}, nil).borrowHistory(day, 3, 4); got != nil {
+		t.Errorf("没有快照存储时返回 %v，期望 nil", got)
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// 搜索结果的排序方式
+const (
+	SortLeastOccupied = "least_occupied" // 当天被占用节次最少的优先
+	SortLargest       = "largest"        // 座位数最多的优先
+	SortBuilding      = "building"       // 按教学楼优先顺序
+)
+
+// DefaultSearchLimit 搜索结果默认返回的教室数
+const DefaultSearchLimit = 50
+
+// searchConcurrency 跨教学楼搜索时同时查询的教学楼数
+const searchConcurrency = 4
+
+// ErrBadSearch 搜索条件无效，例如校区或教学楼未配置、没有可搜索的教学楼
+var ErrBadSearch = errors.New("搜索条件无效")
+
+// Campuses 返回配置的校区列表
+func (s *ClassroomService) Campuses() []Campus {
+	return s.cfg.Campuses
+}
+
+// SearchRooms 在多个教学楼中搜索指定时间段空闲的教室并排序
+// 每个教学楼使用一次全天状态查询（与 GetFullDayStatus 共用缓存），
+// 既能判断目标节次是否空闲，也能统计当天被占用的节次数
+// 部分教学楼查询失败时，其余结果照常返回，失败原因记录在 Failed 中
+func (s *ClassroomService) SearchRooms(ctx context.Context, req model.SearchRequest) (*model.SearchResponse, error) {
+	buildings, campusOf, err := s.searchBuildings(req.Campus, req.Buildings)
+	if err != nil {
+		return nil, err
+	}
+	start, _ := strconv.Atoi(req.StartNode)
+	end, _ := strconv.Atoi(req.EndNode)
+	sortBy := req.Sort
+	if sortBy == "" {
+		sortBy = SortLeastOccupied
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	var (
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, searchConcurrency)
+		resps = make([]*model.FullDayStatusResponse, len(buildings))
+		errs  = make([]error, len(buildings))
+	)
+	for i, building := range buildings {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			resps[i], errs[i] = s.GetFullDayStatus(ctx, model.FullDayQueryRequest{BuildingName: building, DateOffset: req.DateOffset})
+		}()
+	}
+	wg.Wait()
+
+	result := &model.SearchResponse{
+		StartNode: req.StartNode,
+		EndNode:   req.EndNode,
+		Sort:      sortBy,
+		Results:   []model.SearchResult{},
+	}
+	// 教学楼名称是模糊匹配，名称相互包含时（如 "综合楼" 与 "综合楼B"）会带出其他教学楼的教室，
+	// 按教室名称归属到配置中最匹配的教学楼，只保留归属于本次搜索的教学楼的教室
+	configured := make([]string, 0, len(campusOf))
+	for b := range campusOf {
+		configured = append(configured, b)
+	}
+	seen := make(map[string]bool)
+	candidates := make([][]model.ClassroomFullStatus, len(buildings))
+	var firstErr error
+	for i, resp := range resps {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[buildings[i]] = errs[i].Error()
+			logger.Warn("搜索空教室时查询 %s 失败：%v", buildings[i], errs[i])
+			continue
+		}
+		if result.Date == "" {
+			result.Date, result.Week, result.DayOfWeek = resp.Date, resp.Week, resp.DayOfWeek
+			result.NodeList = resp.NodeList
+			result.FetchedAt = resp.FetchedAt
+		}
+		if resp.FetchedAt.Before(result.FetchedAt) {
+			result.FetchedAt = resp.FetchedAt
+		}
+		result.Stale = result.Stale || resp.Stale
+
+		for _, room := range resp.Classrooms {
+			if owner := roomBuilding(room.RoomName, configured); owner != "" && owner != buildings[i] {
+				continue
+			}
+			// 名称不以任何教学楼开头时，归属到顺序靠前的教学楼
+			if seen[room.RoomName] || !freeDuring(resp.NodeList, room.Status, start, end) {
+				continue
+			}
+			if (req.MinSeats > 0 && room.Seats < req.MinSeats) || (req.MaxSeats > 0 && room.Seats > req.MaxSeats) {
+				continue
+			}
+			seen[room.RoomName] = true
+			candidates[i] = append(candidates[i], room)
+			result.Results = append(result.Results, model.SearchResult{
+				RoomName:      room.RoomName,
+				Building:      buildings[i],
+				Campus:        campusOf[buildings[i]],
+				Seats:         room.Seats,
+				ExamSeats:     room.ExamSeats,
+				OccupiedNodes: occupiedNodes(room.Status),
+				Status:        room.Status,
+			})
+		}
+	}
+	if result.Date == "" {
+		return nil, fmt.Errorf("搜索空教室失败：%w", firstErr)
+	}
+
+	priority := req.BuildingPriority
+	if len(priority) == 0 {
+		priority = buildings
+	}
+	sortSearchResults(result.Results, sortBy, priority)
+
+	if s.cfg.RecommendCount > 0 {
+		var recs []model.Recommendation
+		for i, resp := range resps {
+			if len(candidates[i]) > 0 {
+				recs = append(recs, s.recommendRooms(resp, candidates[i], start, end, seatNeed{req.MinSeats, req.MaxSeats})...)
+			}
+		}
+		result.Recommend = topRecommendations(recs, s.cfg.RecommendCount)
+	}
+	result.Total = len(result.Results)
+	if len(result.Results) > limit {
+		result.Results = result.Results[:limit]
+	}
+	return result, nil
+}
+
+// searchBuildings 确定要搜索的教学楼：指定了教学楼时使用指定的，否则使用校区（或全部校区）配置的教学楼
+// 只能搜索配置中的教学楼，指定的教学楼未配置时返回 ErrBadSearch；同时返回教学楼所属的校区
+func (s *ClassroomService) searchBuildings(campus string, buildings []string) ([]string, map[string]string, error) {
+	campusOf := make(map[string]string)
+	var candidates []string
+	found := campus == ""
+	for _, c := range s.cfg.Campuses {
+		for _, b := range c.Buildings {
+			if _, ok := campusOf[b]; !ok {
+				campusOf[b] = c.Name
+			}
+		}
+		if campus == "" || c.Name == campus {
+			found = found || c.Name == campus
+			candidates = append(candidates, c.Buildings...)
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("%w：未配置校区 %s", ErrBadSearch, campus)
+	}
+
+	if len(buildings) > 0 {
+		var filtered []string
+		for _, b := range buildings {
+			b = strings.TrimSpace(b)
+			if b == "" {
+				continue
+			}
+			c, ok := campusOf[b]
+			if !ok {
+				return nil, nil, fmt.Errorf("%w：未配置教学楼 %s", ErrBadSearch, b)
+			}
+			// 同时指定了校区时，只保留该校区的教学楼
+			if campus != "" && c != campus {
+				continue
+			}
+			filtered = append(filtered, b)
+		}
+		candidates = filtered
+	}
+
+	var result []string
+	for _, b := range candidates {
+		if !slices.Contains(result, b) {
+			result = append(result, b)
+		}
+	}
+	if len(result) == 0 {
+		return nil, nil, fmt.Errorf("%w：没有可搜索的教学楼，请在配置中设置 service.campuses", ErrBadSearch)
+	}
+	return result, campusOf, nil
+}
+
+// roomBuilding 返回教室名称以之开头的最长教学楼名称，都不匹配时返回空字符串
+func roomBuilding(room string, buildings []string) string {
+	owner := ""
+	for _, b := range buildings {
+		if strings.HasPrefix(room, b) && len(b) > len(owner) {
+			owner = b
+		}
+	}
+	return owner
+}
+
+// freeDuring 判断教室在 [start, end] 节次内是否全部空闲
+// 节次组（如 "0506"）与范围有重叠即视为需要空闲
+func freeDuring(nodes []model.NodeInfo, status []model.RoomStatus, start, end int) bool {
+	matched := false
+	for i, node := range nodes {
+		if !nodeOverlaps(node.NodeName, start, end) {
+			continue
+		}
+		matched = true
+		if i >= len(status) || !model.IsFree(status[i].StatusID) {
+			return false
+		}
+	}
+	return matched
+}
+
+// occupiedNodes 统计当天被占用的节次数
+func occupiedNodes(status []model.RoomStatus) int {
+	n := 0
+	for _, st := range status {
+		if st.StatusID != 0 && !model.IsFree(st.StatusID) {
+			n++
+		}
+	}
+	return n
+}
+
+// sortSearchResults 按排序方式排列搜索结果，相同时依次比较占用节次、座位数和教室名称
+func sortSearchResults(results []model.SearchResult, sortBy string, priority []string) {
+	rank := func(building string) int {
+		if i := slices.Index(priority, building); i >= 0 {
+			return i
+		}
+		return len(priority)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch sortBy {
+		case SortLargest:
+			if a.Seats != b.Seats {
+				return a.Seats > b.Seats
+			}
+		case SortBuilding:
+			if ra, rb := rank(a.Building), rank(b.Building); ra != rb {
+				return ra < rb
+			}
+		}
+		if a.OccupiedNodes != b.OccupiedNodes {
+			return a.OccupiedNodes < b.OccupiedNodes
+		}
+		if a.Seats != b.Seats {
+			return a.Seats > b.Seats
+		}
+		return a.RoomName < b.RoomName
+	})
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+)
+
+var searchCampuses = []Campus{
+	{Name: "东校区", Buildings: []string{"综合楼", "综合楼B"}},
+	{Name: "西校区", Buildings: []string{"老文史楼"}},
+}
+
+// searchRooms 各教室当天四个节次组（0102、0304、0506、0708）的状态
+var searchRooms = map[string][]testRoom{
+	"综合楼": {
+		{"综合楼101(120/60)", []string{"", "", "", ""}},
+		{"综合楼102(40/20)", []string{"◆", "", "", ""}},
+		{"综合楼103(80/40)", []string{"", "", "◆", ""}},
+	},
+	"综合楼B": {
+		{"综合楼B201(60/30)", []string{"◆", "Ｊ", "", ""}},
+	},
+	"老文史楼": {
+		{"老文史楼101(75/30)", []string{"", "", "", "◆"}},
+	},
+}
+
+// newSearchService 模拟教务系统的全天状态查询，jsmc_mh 按前缀模糊匹配教室；
+// delays 为各教学楼的响应延迟，failing 中的教学楼返回连接错误
+func newSearchService(t *testing.T, delays map[string]time.Duration, failing ...string) *ClassroomService {
+	t.Helper()
+	client := newTestClient(t, func(req *http.Request) (*http.Response, error) {
+		form := formOf(t, req)
+		building := form.Get("jsmc_mh")
+		time.Sleep(delays[building])
+		if slices.Contains(failing, building) {
+			return nil, errors.New("connection reset")
+		}
+		var rooms []testRoom
+		for name, rs := range searchRooms {
+			if strings.HasPrefix(name, building) {
+				rooms = append(rooms, rs...)
+			}
+		}
+		day, _ := strconv.Atoi(form.Get("xq"))
+		return htmlResponse(req, statusPage([]int{day}, []string{"0102", "0304", "0506", "0708"}, rooms...)), nil
+	})
+	return newTestService(t, Config{CacheTTL: time.Minute, Campuses: searchCampuses}, client)
+}
+
+type searchHit struct {
+	room, building, campus string
+}
+
+func searchHits(resp *model.SearchResponse) []searchHit {
+	var hits []searchHit
+	for _, r := range resp.Results {
+		hits = append(hits, searchHit{r.RoomName, r.Building, r.Campus})
+	}
+	return hits
+}
+
+var (
+	hit101  = searchHit{"综合楼101", "综合楼", "东校区"}
+	hit102  = searchHit{"综合楼102", "综合楼", "东校区"}
+	hitB201 = searchHit{"综合楼B201", "综合楼B", "东校区"}
+	hitLWS  = searchHit{"老文史楼101", "老文史楼", "西校区"}
+)
+
+func TestSearchRooms(t *testing.T) {
+	useTestCalendar(t, "2025-2026-1", 5)
+
+	tests := []struct {
+		name      string
+		req       model.SearchRequest
+		want      []searchHit
+		wantTotal int
+	}{
+		{
+			name: "默认按占用节次排序",
+			req:  model.SearchRequest{},
+			want: []searchHit{hit101, hitLWS, hit102, hitB201},
+		},
+		{
+			name: "座位最多优先",
+			req:  model.SearchRequest{Sort: SortLargest},
+			want: []searchHit{hit101, hitLWS, hitB201, hit102},
+		},
+		{
+			name: "座位数范围",
+			req:  model.SearchRequest{MinSeats: 50, MaxSeats: 100},
+			want: []searchHit{hitLWS, hitB201},
+		},
+		{
+			name: "指定校区",
+			req:  model.SearchRequest{Campus: "西校区"},
+			want: []searchHit{hitLWS},
+		},
+		{
+			name: "指定教学楼，名称被包含的教学楼不带出其他楼的教室",
+			req:  model.SearchRequest{Buildings: []string{"综合楼"}},
+			want: []searchHit{hit101, hit102},
+		},
+		{
+			name: "教学楼优先顺序",
+			req:  model.SearchRequest{Sort: SortBuilding, BuildingPriority: []string{"老文史楼", "综合楼B"}},
+			want: []searchHit{hitLWS, hitB201, hit101, hit102},
+		},
+		{
+			name:      "限制返回数量",
+			req:       model.SearchRequest{Limit: 2},
+			want:      []searchHit{hit101, hitLWS},
+			wantTotal: 4,
+		},
+	}
+	s := newSearchService(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.StartNode, req.EndNode = "05", "06"
+			resp, err := s.SearchRooms(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := searchHits(resp); !slices.Equal(got, tt.want) {
+				t.Errorf("搜索结果 = %v，期望 %v", got, tt.want)
+			}
+			wantTotal := tt.wantTotal
+			if wantTotal == 0 {
+				wantTotal = len(tt.want)
+			}
+			if resp.Total != wantTotal {
+				t.Errorf("Total = %d，期望 %d", resp.Total, wantTotal)
+			}
+		})
+	}
+}
+
+// TestSearchRoomsDeterministic 教室归属的教学楼与各教学楼查询完成的先后无关
+func TestSearchRoomsDeterministic(t *testing.T) {
+	useTestCalendar(t, "2025-2026-1", 5)
+
+	for _, delays := range []map[string]time.Duration{
+		{"综合楼": 20 * time.Millisecond},
+		{"综合楼B": 20 * time.Millisecond},
+		{"老文史楼": 20 * time.Millisecond},
+	} {
+		s := newSearchService(t, delays)
+		resp, err := s.SearchRooms(context.Background(), model.SearchRequest{StartNode: "05", EndNode: "06"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := searchHits(resp), []searchHit{hit101, hitLWS, hit102, hitB201}; !slices.Equal(got, want) {
+			t.Errorf("延迟 %v 时搜索结果 = %v，期望 %v", delays, got, want)
+		}
+	}
+}
+
+func TestSearchRoomsPartialFailure(t *testing.T) {
+	useTestCalendar(t, "2025-2026-1", 5)
+
+	s := newSearchService(t, nil, "综合楼B")
+	resp, err := s.SearchRooms(context.Background(), model.SearchRequest{StartNode: "05", EndNode: "06"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Failed["综合楼B"]; !ok || len(resp.Failed) != 1 {
+		t.Errorf("Failed = %v，期望只有综合楼B", resp.Failed)
+	}
+	// 综合楼B 查询失败时，综合楼的结果中模糊匹配带出的综合楼B201 也不归到综合楼
+	want := []searchHit{hit101, hitLWS, hit102}
+	if got := searchHits(resp); !slices.Equal(got, want) {
+		t.Errorf("搜索结果 = %v，期望 %v", got, want)
+	}
+
+	s = newSearchService(t, nil, "综合楼", "综合楼B", "老文史楼")
+	if _, err := s.SearchRooms(context.Background(), model.SearchRequest{StartNode: "05", EndNode: "06"}); err == nil {
+		t.Error("全部教学楼查询失败时应返回错误")
+	}
+}
+
+func TestSearchRoomsBadRequest(t *testing.T) {
+	useTestCalendar(t, "2025-2026-1", 5)
+
+	s := newSearchService(t, nil)
+	for _, req := range []model.SearchRequest{
+		{Campus: "南校区"},
+		{Buildings: []string{"图书馆"}},
+		{Campus: "西校区", Buildings: []string{"综合楼"}},
+	} {
+		req.StartNode, req.EndNode = "05", "06"
+		if _, err := s.SearchRooms(context.Background(), req); !errors.Is(err, ErrBadSearch) {
+			t.Errorf("%+v：返回 %v，期望 ErrBadSearch", req, err)
+		}
+	}
+}
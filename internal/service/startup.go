@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// StartupPhase 启动阶段
+type StartupPhase string
+
+const (
+	StartupWaitingCredentials StartupPhase = "waiting_credentials" // 未设置账号密码，等待通过管理接口设置
+	StartupLoggingIn          StartupPhase = "logging_in"          // 正在登录统一认证
+	StartupLoadingCalendar    StartupPhase = "loading_calendar"    // 已登录，正在获取学期和周次
+	StartupReady              StartupPhase = "ready"               // 启动完成，可以正常查询
+)
+
+// 启动失败后的重试间隔，每次失败翻倍
+const (
+	startupRetryMin = 5 * time.Second
+	startupRetryMax = 2 * time.Minute
+)
+
+// StartupState 启动状态快照
+type StartupState struct {
+	Phase       StartupPhase `json:"phase"`
+	Message     string       `json:"message"`
+	Attempt     int          `json:"attempt"`              // 当前阶段的尝试次数
+	LastError   string       `json:"last_error,omitempty"` // 最近一次失败的原因
+	NextRetryAt *time.Time   `json:"next_retry_at,omitempty"`
+	ReadyAt     *time.Time   `json:"ready_at,omitempty"`
+}
+
+// Startup 在后台完成登录和日历初始化，失败时按退避间隔重试
+// 完成之前 HTTP 服务已经可以访问，查询接口返回 503 并说明原因
+type Startup struct {
+	client       *cas.Client
+	cfg          Config
+	loginTimeout time.Duration
+	onReady      func()
+
+	mu    sync.RWMutex
+	state StartupState
+	wake  chan struct{}
+}
+
+// NewStartup 创建启动流程，loginTimeout 为单次登录的超时，onReady 在启动完成时调用
+func NewStartup(client *cas.Client, cfg Config, loginTimeout time.Duration, onReady func()) *Startup {
+	return &Startup{
+		client:       client,
+		cfg:          cfg,
+		loginTimeout: loginTimeout,
+		onReady:      onReady,
+		state:        StartupState{Phase: StartupLoggingIn, Message: phaseMessage(StartupLoggingIn)},
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+func phaseMessage(p StartupPhase) string {
+	switch p {
+	case StartupWaitingCredentials:
+		return "未设置教务系统账号，请联系管理员"
+	case StartupLoggingIn:
+		return "正在登录教务系统，请稍候"
+	case StartupLoadingCalendar:
+		return "正在获取学期和周次信息，请稍候"
+	case StartupReady:
+		return "服务正常"
+	}
+	return ""
+}
+
+// State 返回当前启动状态
+func (s *Startup) State() StartupState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// Ready 返回启动是否完成
+func (s *Startup) Ready() bool {
+	return s.State().Phase == StartupReady
+}
+
+// Retry 跳过当前的等待立即重试，例如管理员更新了账号密码之后
+func (s *Startup) Retry() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Startup) setPhase(p StartupPhase, attempt int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.Phase != p {
+		s.state.LastError = ""
+	}
+	s.state.Phase = p
+	s.state.Message = phaseMessage(p)
+	s.state.Attempt = attempt
+	s.state.NextRetryAt = nil
+	if p == StartupReady {
+		now := time.Now()
+		s.state.ReadyAt = &now
+	}
+}
+
+func (s *Startup) setFailure(err error, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.LastError = err.Error()
+	if !next.IsZero() {
+		s.state.NextRetryAt = &next
+	}
+}
+
+// wait 等待 d 或被 Retry 唤醒，ctx 取消时返回 false
+func (s *Startup) wait(ctx context.Context, d time.Duration) bool {
+	var timeout <-chan time.Time
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-s.wake:
+	case <-timeout:
+	}
+	return true
+}
+
+// Run 依次登录、初始化日历，直到成功或 ctx 取消
+func (s *Startup) Run(ctx context.Context) {
+	if !s.runStep(ctx, StartupLoggingIn, s.login) {
+		return
+	}
+	if !s.runStep(ctx, StartupLoadingCalendar, s.loadCalendar) {
+		return
+	}
+	s.setPhase(StartupReady, 0)
+	logger.Info("启动完成，服务已可以正常查询。")
+	if s.onReady != nil {
+		s.onReady()
+	}
+}
+
+// runStep 执行一个启动步骤，失败时按退避间隔重试，ctx 取消时返回 false
+func (s *Startup) runStep(ctx context.Context, phase StartupPhase, step func(ctx context.Context) error) bool {
+	delay := startupRetryMin
+	for attempt := 1; ; attempt++ {
+		s.setPhase(phase, attempt)
+		err := step(ctx)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if errors.Is(err, cas.ErrNoCredentials) {
+			// 没有账号密码时重试没有意义，等待管理员设置后唤醒
+			s.setPhase(StartupWaitingCredentials, attempt)
+			logger.Warn("未设置账号密码（QFNU_USER/QFNU_PASS 或 -u/-p），可通过管理接口 PUT /api/admin/credentials 设置。")
+			if !s.wait(ctx, 0) {
+				return false
+			}
+			attempt = 0
+			continue
+		}
+
+		s.setFailure(err, time.Now().Add(delay))
+		logger.Warn("%s失败（第 %d 次）：%v，%v 后重试", phaseAction(phase), attempt, err, delay)
+		if !s.wait(ctx, delay) {
+			return false
+		}
+		delay = min(delay*2, startupRetryMax)
+	}
+}
+
+func phaseAction(p StartupPhase) string {
+	if p == StartupLoadingCalendar {
+		return "初始化日历"
+	}
+	return "登录"
+}
+
+func (s *Startup) login(ctx context.Context) error {
+	// 管理员可能已经通过管理接口登录成功
+	if s.client.SessionState().LoggedIn {
+		return nil
+	}
+	logger.Info("正在尝试登录 QFNU CAS...")
+	ctx, cancel := context.WithTimeout(ctx, s.loginTimeout)
+	defer cancel()
+	if err := s.client.LoginSaved(ctx); err != nil {
+		return err
+	}
+	logger.Info("登录成功。")
+	return nil
+}
+
+func (s *Startup) loadCalendar(ctx context.Context) error {
+	// 首次调用创建日历服务，之后的重试只刷新
+	if cal := GetCalendarService(); cal != nil {
+		return cal.Refresh(ctx)
+	}
+	return InitCalendarService(ctx, s.client, s.cfg)
+}
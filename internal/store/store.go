@@ -0,0 +1,177 @@
+// Package store 将全天状态查询结果保存为快照，供推荐评分和占用统计使用
+// 快照以 JSON 文件保存在 <dir>/<学期>/<教学楼>/w<周次>-d<星期>.json，
+// 同一教学楼同一天只保留最后一次查询的结果
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/model"
+)
+
+// Snapshot 某个教学楼某一天的全天状态
+type Snapshot struct {
+	Term       string                      `json:"term"`        // 学年学期 (2025-2026-1)
+	Building   string                      `json:"building"`    // 教学楼名称
+	Date       string                      `json:"date"`        // 日期 (YYYY-MM-DD)
+	Week       int                         `json:"week"`        // 教学周
+	DayOfWeek  int                         `json:"day_of_week"` // 星期几 (1-7)
+	NodeList   []model.NodeInfo            `json:"node_list"`   // 节次列表
+	Classrooms []model.ClassroomFullStatus `json:"classrooms"`  // 各教室全天状态
+	FetchedAt  time.Time                   `json:"fetched_at"`  // 数据从教务系统获取的时间
+}
+
+// FromFullDay 由全天状态查询结果生成快照
+func FromFullDay(resp *model.FullDayStatusResponse) Snapshot {
+	return Snapshot{
+		Term:       resp.CurrentTerm,
+		Building:   resp.Building,
+		Date:       resp.Date,
+		Week:       resp.Week,
+		DayOfWeek:  resp.DayOfWeek,
+		NodeList:   resp.NodeList,
+		Classrooms: resp.Classrooms,
+		FetchedAt:  resp.FetchedAt,
+	}
+}
+
+// Store 基于文件的快照存储，可并发使用
+type Store struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// Open 打开快照目录，不存在时创建
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建快照目录失败：%w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Dir 返回快照目录
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Save 保存快照，覆盖同一教学楼同一天的旧快照
+// 先写临时文件再重命名，进程中途退出不会留下写了一半的文件
+func (s *Store) Save(snap Snapshot) error {
+	if snap.Term == "" || snap.Building == "" || snap.Week <= 0 || snap.DayOfWeek <= 0 {
+		return fmt.Errorf("快照缺少学期、教学楼或日期信息")
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, safeName(snap.Term), safeName(snap.Building))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("w%02d-d%d.json", snap.Week, snap.DayOfWeek))
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load 读取某学期某教学楼第 fromWeek 至 toWeek 周的快照，按周次和星期排序
+// fromWeek、toWeek 为 0 表示不限；没有快照时返回空列表
+func (s *Store) Load(term, building string, fromWeek, toWeek int) ([]Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dir := filepath.Join(s.dir, safeName(term), safeName(building))
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snaps []Snapshot
+	for _, entry := range entries {
+		var week, day int
+		if _, err := fmt.Sscanf(entry.Name(), "w%d-d%d.json", &week, &day); err != nil {
+			continue // 临时文件或其他文件
+		}
+		if (fromWeek > 0 && week < fromWeek) || (toWeek > 0 && week > toWeek) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var snap Snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return nil, fmt.Errorf("解析快照 %s 失败：%w", entry.Name(), err)
+		}
+		snaps = append(snaps, snap)
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		if snaps[i].Week != snaps[j].Week {
+			return snaps[i].Week < snaps[j].Week
+		}
+		return snaps[i].DayOfWeek < snaps[j].DayOfWeek
+	})
+	return snaps, nil
+}
+
+// Buildings 返回某学期有快照的教学楼
+func (s *Store) Buildings(term string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, safeName(term)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buildings []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			buildings = append(buildings, entry.Name())
+		}
+	}
+	sort.Strings(buildings)
+	return buildings, nil
+}
+
+// safeName 将学期或教学楼名称转换为可用作目录名的字符串
+func safeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
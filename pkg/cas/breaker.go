@@ -0,0 +1,198 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// ErrCircuitOpen 表示上游已熔断，请求被直接拒绝
+var ErrCircuitOpen = errors.New("教务系统暂时不可用")
+
+// CircuitOpenError 请求因熔断被快速拒绝
+type CircuitOpenError struct {
+	Host       string        // 被熔断的上游主机
+	RetryAfter time.Duration // 距离下一次探测的时间
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v（%s 已熔断，%d 秒后重新探测）", ErrCircuitOpen, e.Host, int(e.RetryAfter.Seconds()))
+}
+
+// Is 使 errors.Is(err, ErrCircuitOpen) 成立
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常放行
+	BreakerOpen                         // 熔断，直接拒绝
+	BreakerHalfOpen                     // 半开，放行一个探测请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	FailureThreshold int           // 连续失败多少次后熔断，<= 0 表示不启用
+	OpenTimeout      time.Duration // 熔断后多久放行一个探测请求
+}
+
+// DefaultBreakerConfig 返回默认熔断配置
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+}
+
+// WithCircuitBreaker 启用按上游主机区分的熔断器
+func WithCircuitBreaker(cfg BreakerConfig) ClientOption {
+	return func(o *clientOptions) {
+		o.breaker = cfg
+	}
+}
+
+// breaker 单个上游主机的熔断器
+type breaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool // 半开状态下是否已有探测请求在途
+}
+
+// allow 判断请求是否放行
+func (b *breaker) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		wait := b.cfg.OpenTimeout - b.now().Sub(b.openedAt)
+		if wait > 0 {
+			return &CircuitOpenError{Host: host, RetryAfter: max(wait, time.Second).Round(time.Second)}
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		logger.Info("熔断器半开，放行探测请求：%s", host)
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{Host: host, RetryAfter: time.Second}
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// record 记录请求结果
+func (b *breaker) record(host string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		if b.state != BreakerClosed {
+			logger.Info("探测成功，熔断器恢复：%s", host)
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		if b.state != BreakerOpen {
+			logger.Warn("%s 连续失败 %d 次，熔断 %v", host, b.failures, b.cfg.OpenTimeout)
+		}
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// skip 本次请求不计入统计（如调用方取消），仅释放探测名额
+func (b *breaker) skip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// breakerSet 按上游主机管理熔断器
+type breakerSet struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// newBreakerSet 根据配置创建熔断器集合，未启用时返回 nil
+func newBreakerSet(cfg BreakerConfig) *breakerSet {
+	if cfg.FailureThreshold <= 0 {
+		return nil
+	}
+	return &breakerSet{cfg: cfg, now: time.Now, breakers: make(map[string]*breaker)}
+}
+
+func (bs *breakerSet) get(host string) *breaker {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	b, ok := bs.breakers[host]
+	if !ok {
+		b = &breaker{cfg: bs.cfg, now: bs.now}
+		bs.breakers[host] = b
+	}
+	return b
+}
+
+// BreakerStates 返回各上游主机的熔断器状态，未启用熔断时返回 nil
+func (c *Client) BreakerStates() map[string]BreakerState {
+	if c.breakers == nil {
+		return nil
+	}
+	c.breakers.mu.Lock()
+	defer c.breakers.mu.Unlock()
+
+	states := make(map[string]BreakerState, len(c.breakers.breakers))
+	for host, b := range c.breakers.breakers {
+		b.mu.Lock()
+		states[host] = b.state
+		b.mu.Unlock()
+	}
+	return states
+}
+
+// upstreamFailed 判断一次请求是否应计为上游故障
+// 调用方主动取消和本地限流拒绝不算上游的问题，但超时算
+func upstreamFailed(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		var overload *OverloadError
+		return !errors.Is(req.Context().Err(), context.Canceled) && !errors.As(err, &overload)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
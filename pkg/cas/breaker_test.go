@@ -0,0 +1,148 @@
+package cas
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testHost = "jwgl.example.com"
+
+// newTestBreaker 创建使用手动时钟的熔断器，返回推进时钟的函数
+func newTestBreaker(cfg BreakerConfig) (*breaker, func(time.Duration)) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	b := &breaker{cfg: cfg, now: func() time.Time { return now }}
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func mustAllow(t *testing.T, b *breaker) {
+	t.Helper()
+	if err := b.allow(testHost); err != nil {
+		t.Fatalf("状态 %v 下请求被拒绝：%v", b.state, err)
+	}
+}
+
+func mustReject(t *testing.T, b *breaker) *CircuitOpenError {
+	t.Helper()
+	err := b.allow(testHost)
+	var open *CircuitOpenError
+	if !errors.As(err, &open) {
+		t.Fatalf("状态 %v 下 allow 返回 %v，期望 *CircuitOpenError", b.state, err)
+	}
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("%v 应满足 errors.Is(err, ErrCircuitOpen)", err)
+	}
+	return open
+}
+
+func wantState(t *testing.T, b *breaker, want BreakerState) {
+	t.Helper()
+	if b.state != want {
+		t.Fatalf("熔断器状态 = %v，期望 %v", b.state, want)
+	}
+}
+
+// TestBreakerLifecycle closed → open → half-open → open → half-open → closed
+func TestBreakerLifecycle(t *testing.T) {
+	b, advance := newTestBreaker(BreakerConfig{FailureThreshold: 3, OpenTimeout: 30 * time.Second})
+
+	// 未达到阈值时保持闭合，成功一次后重新计数
+	for range 2 {
+		mustAllow(t, b)
+		b.record(testHost, false)
+	}
+	mustAllow(t, b)
+	b.record(testHost, true)
+	for range 2 {
+		mustAllow(t, b)
+		b.record(testHost, false)
+	}
+	wantState(t, b, BreakerClosed)
+
+	// 连续第 3 次失败后熔断
+	mustAllow(t, b)
+	b.record(testHost, false)
+	wantState(t, b, BreakerOpen)
+	if open := mustReject(t, b); open.RetryAfter != 30*time.Second || open.Host != testHost {
+		t.Errorf("熔断错误 = %+v，期望 %s 在 30s 后重新探测", open, testHost)
+	}
+	advance(20 * time.Second)
+	if open := mustReject(t, b); open.RetryAfter != 10*time.Second {
+		t.Errorf("RetryAfter = %v，期望 10s", open.RetryAfter)
+	}
+
+	// 超过熔断时间后半开，只放行一个探测请求
+	advance(10 * time.Second)
+	mustAllow(t, b)
+	wantState(t, b, BreakerHalfOpen)
+	mustReject(t, b)
+
+	// 探测失败重新熔断，并重新计时
+	b.record(testHost, false)
+	wantState(t, b, BreakerOpen)
+	if open := mustReject(t, b); open.RetryAfter != 30*time.Second {
+		t.Errorf("重新熔断后 RetryAfter = %v，期望 30s", open.RetryAfter)
+	}
+
+	// 探测成功后恢复
+	advance(30 * time.Second)
+	mustAllow(t, b)
+	b.record(testHost, true)
+	wantState(t, b, BreakerClosed)
+	mustAllow(t, b)
+	mustAllow(t, b)
+}
+
+// TestBreakerSkipReleasesProbe 探测请求被取消时释放探测名额，但不改变状态
+func TestBreakerSkipReleasesProbe(t *testing.T) {
+	b, advance := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	mustAllow(t, b)
+	b.record(testHost, false)
+	wantState(t, b, BreakerOpen)
+
+	advance(time.Minute)
+	mustAllow(t, b)
+	mustReject(t, b)
+	b.skip()
+	wantState(t, b, BreakerHalfOpen)
+
+	mustAllow(t, b)
+	b.record(testHost, true)
+	wantState(t, b, BreakerClosed)
+}
+
+// TestClientCircuitBreaker 上游持续 5xx 时熔断，之后的请求不再到达上游
+func TestClientCircuitBreaker(t *testing.T) {
+	srv, hits := newRetryServer(t, http.StatusInternalServerError)
+	c, err := NewClient(WithCircuitBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func() error {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := c.send(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	for range 2 {
+		if err := send(); err != nil {
+			t.Fatalf("熔断前的请求失败：%v", err)
+		}
+	}
+	if err := send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("连续失败后请求返回 %v，期望 ErrCircuitOpen", err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("上游收到 %d 个请求，熔断后不应再发送", got)
+	}
+
+	u, _ := url.Parse(srv.URL)
+	if got := c.BreakerStates()[u.Host]; got != BreakerOpen {
+		t.Errorf("BreakerStates()[%s] = %v，期望 open", u.Host, got)
+	}
+}
@@ -0,0 +1,214 @@
+package cas
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CaptchaSolver 验证码识别接口
+// 登录需要验证码时，Client 会从统一认证拉取验证码图片并交给 Solver，
+// Solver 返回识别结果，作为登录表单的 captcha 字段提交
+type CaptchaSolver interface {
+	Solve(ctx context.Context, image []byte) (string, error)
+}
+
+// CaptchaSolverFunc 允许直接使用函数作为 CaptchaSolver
+type CaptchaSolverFunc func(ctx context.Context, image []byte) (string, error)
+
+// Solve 实现 CaptchaSolver 接口
+func (f CaptchaSolverFunc) Solve(ctx context.Context, image []byte) (string, error) {
+	return f(ctx, image)
+}
+
+// ErrCaptchaNotPending 表示当前没有等待人工输入的验证码
+var ErrCaptchaNotPending = errors.New("当前没有等待输入的验证码")
+
+// CaptchaChallenge 一次等待人工输入的验证码
+type CaptchaChallenge struct {
+	ID        string    // 挑战 ID，提交答案时需要回传，防止答错题
+	Image     []byte    // 验证码图片原始数据
+	CreatedAt time.Time // 创建时间
+}
+
+// ManualCaptchaSolver 人工验证码识别器
+// Solve 会挂起当前登录流程，直到运维人员在管理页面看到图片并提交答案
+type ManualCaptchaSolver struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending *CaptchaChallenge
+	answer  chan string
+}
+
+// NewManualCaptchaSolver 创建人工验证码识别器
+// timeout: 等待人工输入的最长时间，0 表示只受 ctx 控制
+func NewManualCaptchaSolver(timeout time.Duration) *ManualCaptchaSolver {
+	return &ManualCaptchaSolver{timeout: timeout}
+}
+
+// Solve 实现 CaptchaSolver 接口
+func (m *ManualCaptchaSolver) Solve(ctx context.Context, image []byte) (string, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	answer := make(chan string, 1)
+	m.mu.Lock()
+	m.pending = &CaptchaChallenge{
+		ID:        id,
+		Image:     image,
+		CreatedAt: time.Now(),
+	}
+	m.answer = answer
+	m.mu.Unlock()
+
+	// 无论结果如何，结束时清除挂起状态，避免管理页面展示过期图片
+	defer func() {
+		m.mu.Lock()
+		if m.pending != nil && m.pending.ID == id {
+			m.pending = nil
+			m.answer = nil
+		}
+		m.mu.Unlock()
+	}()
+
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+
+	select {
+	case a := <-answer:
+		return a, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("等待人工输入验证码超时: %w", ctx.Err())
+	}
+}
+
+// Pending 返回当前等待输入的验证码
+func (m *ManualCaptchaSolver) Pending() (*CaptchaChallenge, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending == nil {
+		return nil, false
+	}
+	c := *m.pending
+	return &c, true
+}
+
+// Submit 提交人工识别的验证码答案
+func (m *ManualCaptchaSolver) Submit(id, answer string) error {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return errors.New("验证码不能为空")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending == nil || m.pending.ID != id {
+		return ErrCaptchaNotPending
+	}
+
+	// answer 带一个缓冲，重复提交时丢弃后来的答案
+	select {
+	case m.answer <- answer:
+	default:
+	}
+	return nil
+}
+
+// CommandCaptchaSolver 调用本地命令识别验证码
+// 验证码图片通过 stdin 传入，命令在 stdout 输出识别结果
+type CommandCaptchaSolver struct {
+	name string
+	args []string
+}
+
+// NewCommandCaptchaSolver 创建命令行验证码识别器
+// 例如：NewCommandCaptchaSolver("python3", "ocr.py")
+func NewCommandCaptchaSolver(name string, args ...string) *CommandCaptchaSolver {
+	return &CommandCaptchaSolver{name: name, args: args}
+}
+
+// Solve 实现 CaptchaSolver 接口
+func (s *CommandCaptchaSolver) Solve(ctx context.Context, image []byte) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.name, s.args...)
+	cmd.Stdin = bytes.NewReader(image)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("验证码识别命令执行失败: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+
+	answer := strings.TrimSpace(stdout.String())
+	if answer == "" {
+		return "", errors.New("验证码识别命令没有输出结果")
+	}
+	return answer, nil
+}
+
+// fetchCaptcha 拉取验证码图片
+// 验证码与统一认证的会话绑定，必须复用同一个 CookieJar
+func (c *Client) fetchCaptcha(ctx context.Context) ([]byte, error) {
+	captchaURL := fmt.Sprintf("%s?%d", URLCaptcha, time.Now().UnixMilli())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", captchaURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建验证码请求失败: %w", err)
+	}
+
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取验证码失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("验证码接口异常: %d", resp.StatusCode)
+	}
+
+	image, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取验证码图片失败: %w", err)
+	}
+	if len(image) == 0 {
+		return nil, errors.New("验证码图片为空")
+	}
+	return image, nil
+}
+
+// solveCaptcha 拉取验证码并交给配置的 Solver 识别
+func (c *Client) solveCaptcha(ctx context.Context) (string, error) {
+	image, err := c.fetchCaptcha(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	answer, err := c.options.captchaSolver.Solve(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("验证码识别失败: %w", err)
+	}
+	return answer, nil
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -8,8 +8,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/cookiejar"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
@@ -17,8 +17,16 @@ import (
 
 const (
 	DefaultTimeout = 30 * time.Second
-	// SessionExpiredMark 是检测 Session 失效的关键字
+	// DefaultLoginTimeout 自动重登录的总超时，与触发重登录的请求无关
+	DefaultLoginTimeout = 1 * time.Minute
+	// SessionExpiredMark 是登录页标题中的关键字，见 DefaultSessionValidator
 	SessionExpiredMark = "用户登录"
+
+	// casHost 统一认证主机，业务请求被重定向到这里说明 Session 已失效
+	casHost = "ids.qfnu.edu.cn"
+	// sessionCheckPrefix 检测 Session 状态时预读的响应体长度
+	// 教务系统登录页的标识位于 <head> 中，8KB 足够覆盖
+	sessionCheckPrefix = 8 << 10
 )
 
 // Client 封装了 CAS 登录和后续请求的 HTTP 客户端
@@ -26,16 +34,57 @@ const (
 type Client struct {
 	httpClient *http.Client
 	options    *clientOptions
+	limiter    *limiter    // 为 nil 表示不限流
+	breakers   *breakerSet // 为 nil 表示不熔断
+	retries    atomic.Int64
 
-	// 互斥锁，用于并发重登录时的线程安全
+	// mu 保护账号密码，只在读写凭据时短暂持有
 	mu sync.Mutex
 	// 保存账号密码，用于自动重登录
 	username string
 	password string
+
+	// loginMu 保证同一时间只有一个登录流程
+	// 登录可能要等待人工输入验证码，因此与 mu 分开，登录期间仍可以更新凭据
+	loginMu sync.Mutex
+
+	// 进行中的自动重登录，并发检测到 Session 失效的请求共享同一次重登录的结果
+	reloginMu sync.Mutex
+	relogin   *reloginCall
+
+	// 会话状态，供管理接口查看
+	stateMu sync.RWMutex
+	state   SessionState
+}
+
+// SessionState 会话状态快照
+type SessionState struct {
+	Username      string    // 当前使用的账号
+	Role          Role      // 账号角色（自动识别模式下登录成功后确定）
+	LoggedIn      bool      // 最近一次登录是否成功
+	LoginAt       time.Time // 最近一次登录成功的时间
+	LastFailureAt time.Time // 最近一次登录失败的时间
+	LastFailure   string    // 最近一次登录失败的原因
+	LoginCount    int       // 登录成功次数（含自动重登录）
+	FailureCount  int       // 登录失败次数
 }
 
 type clientOptions struct {
-	timeout time.Duration
+	timeout       time.Duration
+	loginTimeout  time.Duration
+	captchaSolver CaptchaSolver
+	role          Role
+
+	// 上游限流，见 limiter.go
+	rateLimit   float64
+	rateBurst   int
+	maxInFlight int
+	maxQueue    int
+
+	retry            RetryPolicy
+	breaker          BreakerConfig
+	sessionValidator SessionValidator
+	observer         Observer
 }
 
 // ClientOption 定义配置选项函数类型 (Functional Options Pattern)
@@ -48,16 +97,38 @@ func WithTimeout(d time.Duration) ClientOption {
 	}
 }
 
+// WithLoginTimeout 设置自动重登录的总超时
+// 重登录在后台进行，不受触发它的请求的 context 影响；使用人工验证码时需要留出等待输入的时间
+func WithLoginTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.loginTimeout = d
+	}
+}
+
+// WithCaptchaSolver 设置验证码识别器
+// 未设置时，遇到验证码会直接返回错误，需人工在浏览器登录消除验证状态
+func WithCaptchaSolver(s CaptchaSolver) ClientOption {
+	return func(o *clientOptions) {
+		o.captchaSolver = s
+	}
+}
+
 // NewClient 创建一个新的 CAS 客户端
 func NewClient(opts ...ClientOption) (*Client, error) {
 	// 默认配置
 	options := &clientOptions{
-		timeout: DefaultTimeout,
+		timeout:          DefaultTimeout,
+		loginTimeout:     DefaultLoginTimeout,
+		sessionValidator: NewDefaultSessionValidator(),
+		observer:         nopObserver{},
 	}
 
 	for _, opt := range opts {
 		opt(options)
 	}
+	if _, ok := portals[options.role]; !ok && options.role != RoleAuto {
+		return nil, fmt.Errorf("未知的账号角色: %s", options.role)
+	}
 
 	// 初始化 CookieJar
 	jar, err := cookiejar.New(nil)
@@ -81,6 +152,10 @@ func NewClient(opts ...ClientOption) (*Client, error) {
 	return &Client{
 		httpClient: httpClient,
 		options:    options,
+		limiter:    newLimiter(options),
+		breakers:   newBreakerSet(options.breaker),
+		// 指定角色时无需等待登录识别
+		state: SessionState{Role: options.role},
 	}, nil
 }
 
@@ -90,114 +165,203 @@ func (c *Client) GetClient() *http.Client {
 }
 
 // Do 发送 HTTP 请求 (代理方法)，增加了 Session 失效自动重试机制
+// 响应体不会被整体读入内存，调用方可以直接流式解析
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	// 1. 如果请求有 Body，我们需要先缓存它，因为 Body 是 io.ReadCloser，读完就没了
-	// 如果需要重试，我们必须能够重新读取 Body
-	var bodyBytes []byte
-	if req.Body != nil {
-		var err error
-		bodyBytes, err = io.ReadAll(req.Body)
-		if err != nil {
-			return nil, fmt.Errorf("读取请求体失败: %w", err)
-		}
-		// 恢复原请求的 Body，以便第一次发送
-		req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	// 1. 确保请求体可以重放，重试和重登录后需要重新发送
+	// http.NewRequest 对 strings.Reader / bytes.Reader 等会自动设置 GetBody，无需额外拷贝
+	if err := ensureGetBody(req); err != nil {
+		return nil, err
 	}
 
-	// 2. 执行原始请求
-	resp, err := c.httpClient.Do(req)
+	// 2. 执行原始请求（经过限流，临时故障按策略重试）
+	resp, err := c.doWithRetry(req)
 	if err != nil {
 		return nil, err
 	}
 
-	// 3. 检查响应是否包含 Session 失效的标识
-	// 读取响应 Body
-	respBodyBytes, err := io.ReadAll(resp.Body)
+	// 3. 检查 Session 状态：最终地址、重定向链、登录页和"非法访问"页特征
+	// 只预读有限的前缀，剩余部分保持流式
+	status, err := c.inspectSession(resp)
 	if err != nil {
 		resp.Body.Close()
 		return nil, fmt.Errorf("读取响应体失败: %w", err)
 	}
-	resp.Body.Close() // 先关闭，后面我们会重新赋值一个新的 Body
-
-	// 检查是否包含 "用户登录"
-	// 注意：这里简单地检查字符串。如果页面结构复杂，可能需要更严谨的检查，但通常足够了
-	respBodyStr := string(respBodyBytes)
-	if strings.Contains(respBodyStr, SessionExpiredMark) {
-		logger.Warn("检测到 Session 可能已失效（响应包含 '%s'），尝试自动重登录...", SessionExpiredMark)
-
-		// 4. 尝试自动重登录
-		if loginErr := c.retryWithReLogin(req.Context()); loginErr != nil {
-			logger.Error("自动重登录失败: %v", loginErr)
-			// 重登录失败，把刚才读取的 Body 恢复回去，返回原始的（失效的）响应给调用者
-			// 这样调用者至少能看到是为什么失败（比如验证码拦截等）
-			resp.Body = io.NopCloser(bytes.NewReader(respBodyBytes))
-			return resp, nil // 或者 return nil, loginErr ? 这里选择返回原响应更符合 HTTP 语义
-		}
+	switch status {
+	case SessionValid:
+		return resp, nil
+	case SessionNoPermission:
+		// 无权限与登录状态无关，重登录也解决不了，直接告知调用方
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrNoPermission, req.URL.Path)
+	}
 
-		logger.Info("自动重登录成功，正在重试请求...")
+	ctx := req.Context()
+	logger.WarnCtx(ctx, "检测到 Session 已失效，尝试自动重登录", "path", req.URL.Path)
 
-		// 5. 重登录成功，克隆并重试请求
-		retryReq, err := c.cloneRequest(req, bodyBytes)
-		if err != nil {
-			return nil, fmt.Errorf("创建重试请求失败: %w", err)
-		}
+	// 4. 尝试自动重登录
+	if loginErr := c.retryWithReLogin(ctx); loginErr != nil {
+		logger.ErrorCtx(ctx, "自动重登录失败", "error", loginErr.Error())
+		// 失效的响应是登录页，交给调用方只会被当作空结果解析
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w，自动重登录失败: %w", ErrSessionExpired, loginErr)
+	}
+	resp.Body.Close()
 
-		// 执行重试请求
-		// 注意：这里直接调用 c.httpClient.Do，避免递归调用 c.Do 导致死循环（虽然 Session 应该已经有效了）
-		retryResp, err := c.httpClient.Do(retryReq)
-		if err != nil {
-			return nil, fmt.Errorf("重试请求失败: %w", err)
-		}
-		return retryResp, nil
+	logger.InfoCtx(ctx, "自动重登录成功，正在重试请求", "path", req.URL.Path)
+
+	// 5. 重登录成功，克隆并重试请求
+	retryReq, err := c.cloneRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("创建重试请求失败: %w", err)
 	}
 
-	// 6. 如果不需要重登录，恢复响应 Body 并返回
-	resp.Body = io.NopCloser(bytes.NewReader(respBodyBytes))
-	return resp, nil
+	// 执行重试请求
+	// 注意：这里直接调用 c.doWithRetry，避免递归调用 c.Do 导致死循环（虽然 Session 应该已经有效了）
+	retryResp, err := c.doWithRetry(retryReq)
+	if err != nil {
+		return nil, fmt.Errorf("重试请求失败: %w", err)
+	}
+	return retryResp, nil
 }
 
-// retryWithReLogin 尝试使用保存的凭据重新登录
-// 这个方法是线程安全的
-func (c *Client) retryWithReLogin(ctx context.Context) error {
+// ensureGetBody 为没有 GetBody 的请求缓存请求体，使其可以重放
+func ensureGetBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+
+	bodyBytes, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("读取请求体失败: %w", err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(bodyBytes)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// SessionState 返回当前会话状态
+func (c *Client) SessionState() SessionState {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.state
+}
+
+// SetCredentials 更新用于登录的账号密码，下一次登录或自动重登录时生效
+func (c *Client) SetCredentials(username, password string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.username = username
+	c.password = password
+}
+
+// ReLogin 使用保存的凭据立即重新登录
+func (c *Client) ReLogin(ctx context.Context) error {
+	return c.retryWithReLogin(ctx)
+}
 
-	// 检查是否有凭据
-	if c.username == "" || c.password == "" {
-		return errors.New("无凭据，无法自动重登录")
+// recordLogin 记录一次登录结果
+func (c *Client) recordLogin(username string, role Role, err error) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.state.Username = username
+	if err != nil {
+		c.state.LoggedIn = false
+		c.state.LastFailureAt = time.Now()
+		c.state.LastFailure = err.Error()
+		c.state.FailureCount++
+		return
 	}
+	c.state.LoggedIn = true
+	c.state.Role = role
+	c.state.LoginAt = time.Now()
+	c.state.LoginCount++
+}
+
+// ErrNoCredentials 未设置账号密码
+var ErrNoCredentials = errors.New("未设置账号密码")
+
+// LoginSaved 使用保存的凭据（Login 或 SetCredentials 设置）登录
+// 与 ReLogin 不同，不计入重新登录次数，用于启动时的首次登录
+func (c *Client) LoginSaved(ctx context.Context) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	return c.loginSaved(ctx)
+}
 
-	// 调用 Login 进行重登录
-	// 注意：Login 方法内部会更新 CookieJar
-	// 我们传入 context，但要注意如果原请求的 context 快超时了，这里可能也会失败
-	// 可以考虑创建一个新的 context 或者继承原 context
-	if err := c.Login(ctx, c.username, c.password); err != nil {
-		return err
+// loginSaved 读取保存的凭据并登录，调用方需持有 c.loginMu
+func (c *Client) loginSaved(ctx context.Context) error {
+	c.mu.Lock()
+	username, password := c.username, c.password
+	c.mu.Unlock()
+	if username == "" || password == "" {
+		return ErrNoCredentials
 	}
+	return c.loginAndRecord(ctx, username, password)
+}
 
-	return nil
+// reloginCall 一次进行中的自动重登录
+type reloginCall struct {
+	done chan struct{} // 重登录结束后关闭
+	err  error
 }
 
-// cloneRequest 克隆一个 HTTP 请求，用于重试
-func (c *Client) cloneRequest(req *http.Request, bodyBytes []byte) (*http.Request, error) {
-	// 创建新的 Request
-	// Context 使用原请求的 Context
-	var newBody io.Reader
-	if bodyBytes != nil {
-		newBody = bytes.NewReader(bodyBytes)
+// retryWithReLogin 尝试使用保存的凭据重新登录
+// 同一时间只进行一次重登录，期间检测到 Session 失效的请求等待并共享其结果；
+// 重登录使用独立的超时（见 WithLoginTimeout），不会因为第一个请求超时或取消而中断，
+// ctx 只决定当前调用方最多等待多久
+func (c *Client) retryWithReLogin(ctx context.Context) error {
+	c.reloginMu.Lock()
+	call := c.relogin
+	if call == nil {
+		call = &reloginCall{done: make(chan struct{})}
+		c.relogin = call
+		// 保留 ctx 中的值，但不继承取消
+		go c.runReLogin(context.WithoutCancel(ctx), call)
 	}
+	c.reloginMu.Unlock()
 
-	newReq, err := http.NewRequestWithContext(req.Context(), req.Method, req.URL.String(), newBody)
-	if err != nil {
-		return nil, err
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	// 复制 Header
-	for key, values := range req.Header {
-		for _, value := range values {
-			newReq.Header.Add(key, value)
-		}
+// runReLogin 执行一次重登录并通知所有等待者
+func (c *Client) runReLogin(ctx context.Context, call *reloginCall) {
+	ctx, cancel := context.WithTimeout(ctx, c.options.loginTimeout)
+	defer cancel()
+
+	c.loginMu.Lock()
+	err := c.loginSaved(ctx)
+	c.loginMu.Unlock()
+	if errors.Is(err, ErrNoCredentials) {
+		err = fmt.Errorf("无法自动重登录：%w", err)
+	} else {
+		c.options.observer.ObserveReLogin(err)
 	}
 
+	c.reloginMu.Lock()
+	call.err = err
+	c.relogin = nil
+	c.reloginMu.Unlock()
+	close(call.done)
+}
+
+// cloneRequest 克隆一个 HTTP 请求，用于重试
+func (c *Client) cloneRequest(req *http.Request) (*http.Request, error) {
+	// Context 和 Header 沿用原请求，请求体通过 GetBody 重新获取
+	newReq := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		newReq.Body = body
+	}
 	return newReq, nil
 }
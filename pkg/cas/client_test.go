@@ -0,0 +1,39 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDoReLoginFailure 会话失效且无法重登录时返回错误，而不是把登录页当作正常响应
+func TestDoReLoginFailure(t *testing.T) {
+	page := readTestdata(t, "jwgl_login.html")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+"/jsxsd/kbxx/jsjy_query2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("重登录失败时应返回错误")
+	}
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("错误 %v 应包含 ErrSessionExpired", err)
+	}
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("错误 %v 应包含重登录失败的原因 ErrNoCredentials", err)
+	}
+}
@@ -0,0 +1,219 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded 表示上游请求排队已满，调用方应稍后重试
+var ErrOverloaded = errors.New("上游请求过多，请稍后重试")
+
+// OverloadError 请求因排队已满被快速拒绝
+type OverloadError struct {
+	RetryAfter time.Duration // 建议的重试等待时间
+}
+
+func (e *OverloadError) Error() string {
+	return fmt.Sprintf("%v（建议 %d 秒后重试）", ErrOverloaded, int(e.RetryAfter.Seconds()))
+}
+
+// Is 使 errors.Is(err, ErrOverloaded) 成立
+func (e *OverloadError) Is(target error) bool {
+	return target == ErrOverloaded
+}
+
+// WithRateLimit 设置上游请求速率（令牌桶）
+// rps: 每秒允许的请求数，<= 0 表示不限速
+// burst: 令牌桶容量，允许的瞬时突发请求数
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(o *clientOptions) {
+		o.rateLimit = rps
+		o.rateBurst = burst
+	}
+}
+
+// WithMaxInFlight 设置同时进行的上游请求数上限，<= 0 表示不限制
+func WithMaxInFlight(n int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxInFlight = n
+	}
+}
+
+// WithMaxQueue 设置等待令牌或并发名额的最大排队数，超出时立即返回 OverloadError
+// <= 0 表示不限制排队长度
+func WithMaxQueue(n int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxQueue = n
+	}
+}
+
+// limiter 组合了令牌桶限速、并发上限和排队长度限制
+type limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	slots    chan struct{}
+	maxQueue int
+	waiting  atomic.Int32
+}
+
+// newLimiter 根据配置创建 limiter，未配置任何限制时返回 nil
+func newLimiter(o *clientOptions) *limiter {
+	if o.rateLimit <= 0 && o.maxInFlight <= 0 {
+		return nil
+	}
+
+	l := &limiter{maxQueue: o.maxQueue}
+	if o.rateLimit > 0 {
+		l.rate = o.rateLimit
+		l.burst = float64(max(o.rateBurst, 1))
+		l.tokens = l.burst
+		l.last = time.Now()
+	}
+	if o.maxInFlight > 0 {
+		l.slots = make(chan struct{}, o.maxInFlight)
+	}
+	return l
+}
+
+// acquire 等待令牌和并发名额，返回用于归还名额的函数
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.maxQueue > 0 && int(l.waiting.Load()) >= l.maxQueue {
+		return nil, &OverloadError{RetryAfter: l.retryAfter()}
+	}
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+
+	if err := l.waitToken(ctx); err != nil {
+		return nil, err
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-l.slots })
+	}, nil
+}
+
+// waitToken 从令牌桶预留一个令牌，令牌不足时等待
+func (l *limiter) waitToken(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// 放弃等待，归还预留的令牌
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// retryAfter 估算排队消化完所需的时间，至少 1 秒
+func (l *limiter) retryAfter() time.Duration {
+	d := time.Second
+	if l.rate > 0 {
+		d = max(d, time.Duration(float64(l.waiting.Load())/l.rate*float64(time.Second)))
+	}
+	return d.Round(time.Second)
+}
+
+// releaseOnClose 在响应体关闭时归还并发名额
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
+
+// send 经过熔断和限流后发送请求，响应体关闭时释放并发名额
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	var b *breaker
+	if c.breakers != nil {
+		b = c.breakers.get(req.URL.Host)
+		if err := b.allow(req.URL.Host); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.sendLimited(req)
+	if b != nil {
+		switch {
+		case upstreamFailed(req, resp, err):
+			b.record(req.URL.Host, false)
+		case err != nil:
+			b.skip()
+		default:
+			b.record(req.URL.Host, true)
+		}
+	}
+	return resp, err
+}
+
+// sendLimited 经过限流后发送请求
+func (c *Client) sendLimited(req *http.Request) (*http.Response, error) {
+	if c.limiter == nil {
+		return c.roundTrip(req)
+	}
+
+	release, err := c.limiter.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// roundTrip 实际发送请求，并记录耗时（不含排队时间）和状态码
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	c.observeUpstream(req, resp, err, time.Since(start))
+	return resp, err
+}
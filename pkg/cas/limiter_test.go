@@ -0,0 +1,202 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitQueued 等待 l 中排队的请求数达到 n
+func waitQueued(t *testing.T, l *limiter, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for l.waiting.Load() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("排队数 = %d，期望 %d", l.waiting.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimiterQueueOverflow(t *testing.T) {
+	l := newLimiter(&clientOptions{maxInFlight: 1, maxQueue: 1})
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二个请求占满排队名额
+	queued := make(chan error, 1)
+	go func() {
+		r, err := l.acquire(ctx)
+		if err == nil {
+			r()
+		}
+		queued <- err
+	}()
+	waitQueued(t, l, 1)
+
+	// 第三个请求立即被拒绝
+	_, err = l.acquire(ctx)
+	var overload *OverloadError
+	if !errors.As(err, &overload) {
+		t.Fatalf("排队已满时 acquire 返回 %v，期望 *OverloadError", err)
+	}
+	if !errors.Is(err, ErrOverloaded) {
+		t.Errorf("%v 应满足 errors.Is(err, ErrOverloaded)", err)
+	}
+	if overload.RetryAfter < time.Second {
+		t.Errorf("RetryAfter = %v，至少应为 1 秒", overload.RetryAfter)
+	}
+
+	// 名额归还后排队的请求继续执行
+	release()
+	select {
+	case err := <-queued:
+		if err != nil {
+			t.Errorf("排队的请求失败：%v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("归还名额后排队的请求没有继续执行")
+	}
+}
+
+func TestLimiterCancelWhileQueued(t *testing.T) {
+	t.Run("等待并发名额", func(t *testing.T) {
+		l := newLimiter(&clientOptions{maxInFlight: 1})
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := l.acquire(ctx)
+			done <- err
+		}()
+		waitQueued(t, l, 1)
+		cancel()
+
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("取消后 acquire 返回 %v，期望 context.Canceled", err)
+		}
+		waitQueued(t, l, 0)
+	})
+
+	t.Run("等待令牌", func(t *testing.T) {
+		l := newLimiter(&clientOptions{rateLimit: 1, rateBurst: 1})
+		if _, err := l.acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("超时后 acquire 返回 %v，期望 context.DeadlineExceeded", err)
+		}
+
+		// 放弃等待时归还预留的令牌，后续请求不用为它多等一秒
+		l.mu.Lock()
+		tokens := l.tokens
+		l.mu.Unlock()
+		if tokens < -0.5 {
+			t.Errorf("取消后令牌数 = %.2f，预留的令牌没有归还", tokens)
+		}
+	})
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	const limit, workers = 3, 40
+	l := newLimiter(&clientOptions{maxInFlight: limit})
+
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			inFlight.Add(-1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > limit {
+		t.Errorf("同时进行的请求数最多为 %d，超过上限 %d", p, limit)
+	}
+	if n := len(l.slots); n != 0 {
+		t.Errorf("全部完成后仍占用 %d 个名额", n)
+	}
+}
+
+// TestClientMaxInFlight 通过 Client 发送请求时，并发名额在响应体关闭后才归还
+func TestClientMaxInFlight(t *testing.T) {
+	const limit, requests = 2, 10
+
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		io.WriteString(w, "<html><title>ok</title></html>")
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithMaxInFlight(limit))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > limit {
+		t.Errorf("上游同时处理的请求数最多为 %d，超过上限 %d", p, limit)
+	}
+	if n := len(c.limiter.slots); n != 0 {
+		t.Errorf("响应体关闭后仍占用 %d 个名额", n)
+	}
+}
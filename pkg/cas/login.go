@@ -20,21 +20,42 @@ const (
 	// URL 常量
 	URLService = "http://zhjw.qfnu.edu.cn/sso.jsp"
 	URLLogin   = "http://ids.qfnu.edu.cn/authserver/login"
-	// URLMainPage = "http://zhjw.qfnu.edu.cn/jsxsd/framework/xsMain.jsp" // 学生端请使用这个
-	URLMainPage    = "http://zhjw.qfnu.edu.cn/jsxsd/framework/jsMain.jsp" // 教师端请使用这个
-	URLSuccessMark = "教学一体化服务平台"                                          // 登录成功的页面标识
+	URLCaptcha = "http://ids.qfnu.edu.cn/authserver/getCaptcha.htl"
+	// 主页地址按账号角色区分，见 role.go
+	URLSuccessMark = "教学一体化服务平台" // 登录成功的页面标识
 )
 
-// Login 执行完整的 CAS 登录流程
+// Login 执行完整的 CAS 登录流程，并保存凭据用于后续自动重登录
+// 与自动重登录互斥，不会同时进行两个登录流程
 func (c *Client) Login(ctx context.Context, username, password string) error {
-	// 保存凭据用于后续自动重登录
-	// 注意：这里没有加锁，假设 Login 仅在初始化或由 retryWithReLogin（已加锁）调用
+	c.mu.Lock()
 	c.username = username
 	c.password = password
+	c.mu.Unlock()
 
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	return c.loginAndRecord(ctx, username, password)
+}
+
+// loginAndRecord 登录并记录结果，调用方需持有 c.loginMu
+func (c *Client) loginAndRecord(ctx context.Context, username, password string) error {
+	start := time.Now()
+	role, err := c.login(ctx, username, password)
+	c.options.observer.ObserveLogin(err, time.Since(start))
+	c.recordLogin(username, role, err)
+	return err
+}
+
+// login 登录流程的具体步骤，返回识别到的账号角色
+func (c *Client) login(ctx context.Context, username, password string) (Role, error) {
 	// 0. 检查是否需要验证码
-	if err := c.checkNeedCaptcha(ctx, username); err != nil {
-		return err
+	needCaptcha, err := c.checkNeedCaptcha(ctx, username)
+	if err != nil {
+		return RoleAuto, err
+	}
+	if needCaptcha && c.options.captchaSolver == nil {
+		return RoleAuto, errors.New("当前账号需输入验证码，请先在浏览器手动登录一次以消除验证状态")
 	}
 
 	loginPageURL := fmt.Sprintf("%s?service=%s", URLLogin, url.QueryEscape(URLService))
@@ -42,47 +63,52 @@ func (c *Client) Login(ctx context.Context, username, password string) error {
 	// 1. 获取 salt 和 execution
 	salt, execution, err := c.fetchLoginParams(ctx, loginPageURL)
 	if err != nil {
-		return err
+		return RoleAuto, err
 	}
 
 	// 2. 加密密码
 	encPassword, err := auth.EncryptPassword(password, salt)
 	if err != nil {
-		return fmt.Errorf("密码加密失败: %w", err)
+		return RoleAuto, fmt.Errorf("密码加密失败: %w", err)
 	}
 
-	// 3. 提交登录表单并获取 ticket 重定向链接
-	ticketURL, err := c.submitForm(ctx, loginPageURL, username, encPassword, execution)
-	if err != nil {
-		return err
+	// 3. 识别验证码（需在获取登录页之后，验证码与该会话绑定）
+	captcha := ""
+	if needCaptcha {
+		captcha, err = c.solveCaptcha(ctx)
+		if err != nil {
+			return RoleAuto, err
+		}
 	}
 
-	// 4. 完成 SSO 认证流程
-	if err := c.completeSSO(ctx, ticketURL); err != nil {
-		return err
+	// 4. 提交登录表单并获取 ticket 重定向链接
+	ticketURL, err := c.submitForm(ctx, loginPageURL, username, encPassword, execution, captcha)
+	if err != nil {
+		return RoleAuto, err
 	}
 
-	return nil
+	// 5. 完成 SSO 认证流程
+	return c.completeSSO(ctx, ticketURL)
 }
 
 // checkNeedCaptcha 检查账号是否需要验证码
-func (c *Client) checkNeedCaptcha(ctx context.Context, username string) error {
+func (c *Client) checkNeedCaptcha(ctx context.Context, username string) (bool, error) {
 	timestamp := time.Now().UnixMilli()
 	checkURL := fmt.Sprintf("https://ids.qfnu.edu.cn/authserver/checkNeedCaptcha.htl?username=%s&_=%d", username, timestamp)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", checkURL, nil)
 	if err != nil {
-		return fmt.Errorf("创建验证码检查请求失败: %w", err)
+		return false, fmt.Errorf("创建验证码检查请求失败: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.roundTrip(req)
 	if err != nil {
-		return fmt.Errorf("检查验证码状态失败: %w", err)
+		return false, fmt.Errorf("检查验证码状态失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("验证码检查接口异常: %d", resp.StatusCode)
+		return false, fmt.Errorf("验证码检查接口异常: %d", resp.StatusCode)
 	}
 
 	var result struct {
@@ -90,14 +116,10 @@ func (c *Client) checkNeedCaptcha(ctx context.Context, username string) error {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("解析验证码检查响应失败: %w", err)
-	}
-
-	if result.IsNeed {
-		return errors.New("当前账号需输入验证码，请先在浏览器手动登录一次以消除验证状态")
+		return false, fmt.Errorf("解析验证码检查响应失败: %w", err)
 	}
 
-	return nil
+	return result.IsNeed, nil
 }
 
 // fetchLoginParams 获取登录页面所需的动态参数
@@ -107,7 +129,7 @@ func (c *Client) fetchLoginParams(ctx context.Context, url string) (salt, execut
 		return "", "", err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.roundTrip(req)
 	if err != nil {
 		return "", "", fmt.Errorf("访问登录页失败: %w", err)
 	}
@@ -133,7 +155,8 @@ func (c *Client) fetchLoginParams(ctx context.Context, url string) (salt, execut
 }
 
 // submitForm 提交表单，返回携带 ticket 的 URL
-func (c *Client) submitForm(ctx context.Context, loginURL, username, encPassword, execution string) (*url.URL, error) {
+// captcha 为空表示本次登录不需要验证码
+func (c *Client) submitForm(ctx context.Context, loginURL, username, encPassword, execution, captcha string) (*url.URL, error) {
 	formData := url.Values{
 		"username":  {username},
 		"password":  {encPassword},
@@ -143,6 +166,9 @@ func (c *Client) submitForm(ctx context.Context, loginURL, username, encPassword
 		"lt":        {""},
 		"execution": {execution},
 	}
+	if captcha != "" {
+		formData.Set("captcha", captcha)
+	}
 
 	// 创建一个不自动重定向的 Client，用于捕获 302 跳转中的 Ticket
 	// 注意：这里复用 c.httpClient 的 CookieJar，以保持会话
@@ -161,7 +187,9 @@ func (c *Client) submitForm(ctx context.Context, loginURL, username, encPassword
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
+	start := time.Now()
 	resp, err := noRedirectClient.Do(req)
+	c.observeUpstream(req, resp, err, time.Since(start))
 	if err != nil {
 		return nil, fmt.Errorf("提交登录表单失败: %w", err)
 	}
@@ -184,43 +212,35 @@ func (c *Client) submitForm(ctx context.Context, loginURL, username, encPassword
 		return nil, errors.New("账号或密码错误")
 	}
 	if strings.Contains(bodyStr, "验证码") || strings.Contains(bodyStr, "captcha") {
+		if captcha != "" {
+			return nil, errors.New("验证码错误")
+		}
 		return nil, errors.New("系统检测到异常，需要验证码 (需人工介入)")
 	}
 
 	return nil, fmt.Errorf("登录未成功，状态码: %d", resp.StatusCode)
 }
 
-// completeSSO 完成后续的 SSO 跳转和验证
-func (c *Client) completeSSO(ctx context.Context, ticketURL *url.URL) error {
+// completeSSO 完成后续的 SSO 跳转和验证，返回账号角色
+func (c *Client) completeSSO(ctx context.Context, ticketURL *url.URL) (Role, error) {
 	// 1. 访问 Ticket URL
 	if err := c.simpleGet(ctx, ticketURL.String()); err != nil {
-		return fmt.Errorf("Ticket 验证失败: %w", err)
+		return RoleAuto, fmt.Errorf("Ticket 验证失败: %w", err)
 	}
 
 	// 2. 访问 sso.jsp (确保 Cookie 写入)
 	if err := c.simpleGet(ctx, URLService); err != nil {
-		return fmt.Errorf("SSO 初始化失败: %w", err)
+		return RoleAuto, fmt.Errorf("SSO 初始化失败: %w", err)
 	}
 
-	// 3. 访问主页验证最终结果
-	req, err := http.NewRequestWithContext(ctx, "GET", URLMainPage, nil)
-	if err != nil {
-		return err
-	}
-	resp, err := c.httpClient.Do(req)
+	// 3. 访问主页验证最终结果，并确定账号角色
+	role, err := c.verifyPortal(ctx)
 	if err != nil {
-		return fmt.Errorf("访问主页失败: %w", err)
+		return RoleAuto, err
 	}
-	defer resp.Body.Close()
+	log.Println("检测到登录成功标识，登录流程完成。")
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	if !strings.Contains(string(bodyBytes), URLSuccessMark) {
-		return errors.New("登录流程结束，但未检测到登录成功标识")
-	} else {
-		log.Println("检测到登录成功标识，登录流程完成。")
-	}
-
-	return nil
+	return role, nil
 }
 
 func (c *Client) simpleGet(ctx context.Context, urlStr string) error {
@@ -228,7 +248,7 @@ func (c *Client) simpleGet(ctx context.Context, urlStr string) error {
 	if err != nil {
 		return err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.roundTrip(req)
 	if err != nil {
 		return err
 	}
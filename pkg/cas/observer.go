@@ -0,0 +1,67 @@
+package cas
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// Observer 接收 Client 内部事件，用于接入监控系统
+// 实现需要是并发安全的，且不应阻塞
+type Observer interface {
+	// ObserveUpstream 每次实际发往上游的请求（含重试）结束时调用
+	// status 为响应状态码，请求失败时为 0
+	ObserveUpstream(endpoint string, status int, err error, d time.Duration)
+	// ObserveRetry 每次因临时故障重试时调用
+	ObserveRetry(endpoint string)
+	// ObserveLogin 每次完整登录流程结束时调用
+	ObserveLogin(err error, d time.Duration)
+	// ObserveReLogin 每次重新登录（Session 失效自动触发或手动触发）结束时调用
+	ObserveReLogin(err error)
+}
+
+// WithObserver 设置监控事件接收者
+func WithObserver(o Observer) ClientOption {
+	return func(o2 *clientOptions) {
+		o2.observer = o
+	}
+}
+
+// nopObserver 默认的空实现
+type nopObserver struct{}
+
+func (nopObserver) ObserveUpstream(string, int, error, time.Duration) {}
+func (nopObserver) ObserveRetry(string)                               {}
+func (nopObserver) ObserveLogin(error, time.Duration)                 {}
+func (nopObserver) ObserveReLogin(error)                              {}
+
+// EndpointName 返回用于监控标签的上游接口名，取 URL 路径的最后一段
+// 例如 /jsxsd/kbxx/jsjy_query2 -> jsjy_query2，避免查询参数导致标签基数膨胀
+func EndpointName(u *url.URL) string {
+	if u == nil || u.Path == "" || u.Path == "/" {
+		return "root"
+	}
+	return path.Base(u.Path)
+}
+
+// observeUpstream 记录一次上游请求的日志并上报给 Observer
+// 日志使用请求的 context，可以与触发它的 HTTP 请求通过 request_id 关联
+func (c *Client) observeUpstream(req *http.Request, resp *http.Response, err error, d time.Duration) {
+	endpoint := EndpointName(req.URL)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.options.observer.ObserveUpstream(endpoint, status, err, d)
+
+	ctx := req.Context()
+	attrs := []any{"method", req.Method, "host", req.URL.Host, "endpoint", endpoint, "duration_ms", d.Milliseconds()}
+	if err != nil {
+		logger.WarnCtx(ctx, "上游请求失败", append(attrs, "error", err.Error())...)
+		return
+	}
+	logger.InfoCtx(ctx, "上游请求", append(attrs, "status", status)...)
+}
@@ -0,0 +1,43 @@
+package cas
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// URLProbe 检测教务系统是否可达时请求的地址
+const URLProbe = "http://zhjw.qfnu.edu.cn/jsxsd/"
+
+// Probe 检测教务系统是否可达，能收到非 5xx 响应即视为可达
+// 探测请求不经过限流、重试和熔断，不跟随重定向，也不检查 Session，
+// 避免健康检查占用查询名额或影响熔断器的判断
+func (c *Client) Probe(ctx context.Context) error {
+	probeClient := &http.Client{
+		Jar:     c.httpClient.Jar,
+		Timeout: c.httpClient.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URLProbe, nil)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	resp, err := probeClient.Do(req)
+	c.observeUpstream(req, resp, err, time.Since(start))
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("教务系统返回 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package cas
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// RetryPolicy 上游临时故障的重试策略
+// 仅对幂等方法以及 SafePOSTPaths 中列出的只读 POST 接口重试
+type RetryPolicy struct {
+	MaxAttempts     int           // 最大尝试次数（含首次），<= 1 表示不重试
+	BaseDelay       time.Duration // 首次重试的基准等待时间，之后指数增长
+	MaxDelay        time.Duration // 单次等待时间上限
+	RetryableStatus []int         // 需要重试的响应状态码
+	SafePOSTPaths   []string      // 可以安全重试的 POST 接口路径
+}
+
+// DefaultRetryPolicy 返回默认重试策略
+// jsjy_query2 虽然是 POST，但只是查询，重复提交没有副作用
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     3,
+		BaseDelay:       200 * time.Millisecond,
+		MaxDelay:        2 * time.Second,
+		RetryableStatus: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		SafePOSTPaths:   []string{"/jsxsd/kbxx/jsjy_query2"},
+	}
+}
+
+// WithRetryPolicy 设置重试策略，默认不重试
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retry = p
+	}
+}
+
+// retryable 判断该请求是否允许重试
+func (p RetryPolicy) retryable(req *http.Request) bool {
+	if p.MaxAttempts <= 1 {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return slices.Contains(p.SafePOSTPaths, req.URL.Path)
+	}
+	return false
+}
+
+// shouldRetry 根据响应或错误判断是否需要重试，并返回原因用于日志
+func (p RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) (bool, string) {
+	if err != nil {
+		// 调用方已取消或超时、本地限流拒绝、已熔断，重试没有意义
+		if req.Context().Err() != nil {
+			return false, ""
+		}
+		var overload *OverloadError
+		if errors.As(err, &overload) || errors.Is(err, ErrCircuitOpen) {
+			return false, ""
+		}
+		return true, err.Error()
+	}
+	if slices.Contains(p.RetryableStatus, resp.StatusCode) {
+		return true, fmt.Sprintf("状态码 %d", resp.StatusCode)
+	}
+	return false, ""
+}
+
+// backoff 计算第 attempt 次失败后的等待时间（指数退避 + 全抖动）
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
+
+// doWithRetry 发送请求，遇到临时故障按重试策略重试
+// 重试请求通过 req.GetBody 重建请求体
+func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
+	policy := c.options.retry
+	maxAttempts := 1
+	if policy.retryable(req) {
+		maxAttempts = policy.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 {
+			var err error
+			r, err = c.cloneRequest(req)
+			if err != nil {
+				return nil, fmt.Errorf("创建重试请求失败: %w", err)
+			}
+		}
+
+		resp, err := c.send(r)
+		if attempt >= maxAttempts {
+			return resp, err
+		}
+		retry, reason := policy.shouldRetry(r, resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		// 丢弃本次响应，释放连接
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		delay := policy.backoff(attempt)
+		c.retries.Add(1)
+		c.options.observer.ObserveRetry(EndpointName(req.URL))
+		logger.WarnCtx(req.Context(), "上游请求临时失败，稍后重试",
+			"path", req.URL.Path, "attempt", attempt, "max_attempts", maxAttempts, "reason", reason, "delay", delay.Round(time.Millisecond).String())
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// Retries 返回累计的重试次数
+func (c *Client) Retries() int64 {
+	return c.retries.Load()
+}
@@ -0,0 +1,168 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoffBounds(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{10, time.Second},
+		{70, time.Second}, // 移位溢出时取上限
+	}
+	for _, tt := range tests {
+		for range 1000 {
+			d := p.backoff(tt.attempt)
+			if d <= 0 || d > tt.max {
+				t.Fatalf("第 %d 次失败后等待 %v，应在 (0, %v] 之间", tt.attempt, d, tt.max)
+			}
+		}
+	}
+
+	if d := (RetryPolicy{}).backoff(1); d != 0 {
+		t.Errorf("未设置等待时间时 backoff = %v，期望 0", d)
+	}
+}
+
+// newRetryServer 返回依次使用 statuses 作为状态码的测试服务器，超出部分使用最后一个
+func newRetryServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func newRetryClient(t *testing.T, p RetryPolicy) *Client {
+	t.Helper()
+	c, err := NewClient(WithRetryPolicy(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func fastRetryPolicy() RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.BaseDelay = time.Millisecond
+	p.MaxDelay = 5 * time.Millisecond
+	return p
+}
+
+func TestRetryDecisions(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		statuses   []int
+		wantHits   int32
+		wantStatus int
+	}{
+		{"GET 遇到 503 重试到成功", http.MethodGet, "/jsxsd/framework/xsMain.jsp", []int{503, 502, 200}, 3, 200},
+		{"GET 用完尝试次数", http.MethodGet, "/jsxsd/framework/xsMain.jsp", []int{504}, 3, 504},
+		{"只读 POST 重试", http.MethodPost, "/jsxsd/kbxx/jsjy_query2", []int{503, 200}, 2, 200},
+		{"其他 POST 不重试", http.MethodPost, "/jsxsd/xk/LoginToXk", []int{503, 200}, 1, 503},
+		{"4xx 不重试", http.MethodGet, "/jsxsd/framework/xsMain.jsp", []int{404, 200}, 1, 404},
+		{"500 不重试", http.MethodGet, "/jsxsd/framework/xsMain.jsp", []int{500, 200}, 1, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, hits := newRetryServer(t, tt.statuses...)
+			c := newRetryClient(t, fastRetryPolicy())
+
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader("a=1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.doWithRetry(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("请求次数 = %d，期望 %d", got, tt.wantHits)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("状态码 = %d，期望 %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := c.Retries(); got != int64(tt.wantHits-1) {
+				t.Errorf("Retries() = %d，期望 %d", got, tt.wantHits-1)
+			}
+		})
+	}
+}
+
+// TestRetryReplaysBody 重试时重新发送完整的请求体
+func TestRetryReplaysBody(t *testing.T) {
+	var bodies []string
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		bodies = append(bodies, r.PostForm.Encode())
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	c := newRetryClient(t, fastRetryPolicy())
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/jsxsd/kbxx/jsjy_query2", strings.NewReader("xnxqh=2025-2026-1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] != "xnxqh=2025-2026-1" {
+		t.Errorf("两次请求体 = %q，期望相同且完整", bodies)
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	srv, hits := newRetryServer(t, http.StatusServiceUnavailable)
+	p := DefaultRetryPolicy()
+	p.MaxAttempts = 5
+	p.BaseDelay = 10 * time.Second
+	p.MaxDelay = 10 * time.Second
+	c := newRetryClient(t, p)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+
+	start := time.Now()
+	resp, err := c.doWithRetry(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("取消后应返回错误")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("错误 = %v，期望 context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("取消后仍等待了 %v", elapsed)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("请求次数 = %d，取消后不应再重试", got)
+	}
+}
@@ -0,0 +1,139 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// Role 账号角色，决定登录后使用哪一套教务系统门户
+type Role string
+
+const (
+	RoleAuto    Role = ""        // 登录后自动识别
+	RoleTeacher Role = "teacher" // 教师端
+	RoleStudent Role = "student" // 学生端
+)
+
+const (
+	// 教师端门户
+	URLTeacherMainPage = "http://zhjw.qfnu.edu.cn/jsxsd/framework/jsMain.jsp"
+	URLTeacherWeekInfo = "http://zhjw.qfnu.edu.cn/jsxsd/framework/jsMain_new.jsp?t1=1"
+	// 学生端门户
+	URLStudentMainPage = "http://zhjw.qfnu.edu.cn/jsxsd/framework/xsMain.jsp"
+	URLStudentWeekInfo = "http://zhjw.qfnu.edu.cn/jsxsd/framework/xsMain_new.jsp?t1=1"
+
+	// IllegalAccessMark 是角色不匹配或无权限时教务系统返回的页面标识
+	IllegalAccessMark = "非法访问"
+)
+
+// portal 某一角色对应的门户信息
+type portal struct {
+	mainPage    string // 登录后用于校验的主页
+	successMark string // 主页中的登录成功标识
+	weekInfo    string // 教学周信息接口
+}
+
+var portals = map[Role]portal{
+	RoleTeacher: {
+		mainPage:    URLTeacherMainPage,
+		successMark: URLSuccessMark,
+		weekInfo:    URLTeacherWeekInfo,
+	},
+	RoleStudent: {
+		mainPage:    URLStudentMainPage,
+		successMark: URLSuccessMark,
+		weekInfo:    URLStudentWeekInfo,
+	},
+}
+
+// detectOrder 自动识别时依次尝试的角色
+var detectOrder = []Role{RoleTeacher, RoleStudent}
+
+// ParseRole 解析角色字符串，空字符串和 "auto" 表示自动识别
+func ParseRole(s string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return RoleAuto, nil
+	case "teacher":
+		return RoleTeacher, nil
+	case "student":
+		return RoleStudent, nil
+	default:
+		return RoleAuto, fmt.Errorf("未知的账号角色: %s", s)
+	}
+}
+
+// WithRole 指定账号角色
+// 未指定时会在登录后依次尝试教师端、学生端主页自动识别
+func WithRole(r Role) ClientOption {
+	return func(o *clientOptions) {
+		o.role = r
+	}
+}
+
+// Role 返回当前生效的账号角色
+// 自动识别模式下，登录成功前返回 RoleAuto
+func (c *Client) Role() Role {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.state.Role
+}
+
+// WeekInfoURL 返回当前角色对应的教学周信息接口
+// 角色未确定时回退到教师端接口
+func (c *Client) WeekInfoURL() string {
+	if p, ok := portals[c.Role()]; ok {
+		return p.weekInfo
+	}
+	return URLTeacherWeekInfo
+}
+
+// verifyPortal 访问门户主页确认登录成功，并确定账号角色
+func (c *Client) verifyPortal(ctx context.Context) (Role, error) {
+	candidates := detectOrder
+	if c.options.role != RoleAuto {
+		candidates = []Role{c.options.role}
+	}
+
+	var lastErr error
+	for _, role := range candidates {
+		err := c.checkPortal(ctx, portals[role])
+		if err == nil {
+			if c.options.role == RoleAuto {
+				logger.Info("已自动识别账号角色：%s", role)
+			}
+			return role, nil
+		}
+		lastErr = err
+	}
+	return RoleAuto, lastErr
+}
+
+// checkPortal 访问指定门户主页，检查登录成功标识
+func (c *Client) checkPortal(ctx context.Context, p portal) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.mainPage, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		return fmt.Errorf("访问主页失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	body := string(bodyBytes)
+	if strings.Contains(body, IllegalAccessMark) {
+		return errors.New("主页返回非法访问，账号角色与门户不匹配")
+	}
+	if !strings.Contains(body, p.successMark) {
+		return errors.New("登录流程结束，但未检测到登录成功标识")
+	}
+	return nil
+}
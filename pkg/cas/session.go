@@ -0,0 +1,137 @@
+package cas
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+)
+
+// ErrNoPermission 表示会话有效，但当前账号无权访问该页面（教务系统返回"非法访问"）
+// 这种情况重新登录也无济于事，不会触发自动重登录
+var ErrNoPermission = errors.New("当前账号无权限访问该页面")
+
+// ErrSessionExpired 表示会话已失效且自动重登录失败
+var ErrSessionExpired = errors.New("登录已失效")
+
+// SessionStatus 会话校验结果
+type SessionStatus int
+
+const (
+	SessionValid        SessionStatus = iota // 会话有效
+	SessionExpired                           // 会话失效，需要重新登录
+	SessionNoPermission                      // 会话有效但无权限
+)
+
+func (s SessionStatus) String() string {
+	switch s {
+	case SessionValid:
+		return "valid"
+	case SessionExpired:
+		return "expired"
+	case SessionNoPermission:
+		return "no-permission"
+	default:
+		return "unknown"
+	}
+}
+
+// SessionValidator 根据响应判断会话状态
+// prefix 为响应体开头最多 sessionCheckPrefix 字节，仅在 Validate 调用期间有效，
+// 完整响应体仍可从 resp.Body 读取
+type SessionValidator interface {
+	Validate(resp *http.Response, prefix []byte) SessionStatus
+}
+
+// SessionValidatorFunc 允许直接使用函数作为 SessionValidator
+type SessionValidatorFunc func(resp *http.Response, prefix []byte) SessionStatus
+
+// Validate 实现 SessionValidator 接口
+func (f SessionValidatorFunc) Validate(resp *http.Response, prefix []byte) SessionStatus {
+	return f(resp, prefix)
+}
+
+// WithSessionValidator 替换默认的会话校验逻辑
+func WithSessionValidator(v SessionValidator) ClientOption {
+	return func(o *clientOptions) {
+		o.sessionValidator = v
+	}
+}
+
+var (
+	// 登录页标题，例如 <title>用户登录</title>、<title>统一身份认证</title>
+	reLoginTitle = regexp.MustCompile(`<title>[^<]*(` + SessionExpiredMark + `|统一身份认证)[^<]*</title>`)
+	// 登录表单：统一认证的 /authserver/login 或教务系统自带的 /jsxsd/xk/LoginToXk
+	reLoginForm = regexp.MustCompile(`<form[^>]+action="[^"]*(/authserver/login|/jsxsd/xk/LoginToXk)`)
+	// 强智教务系统无权限时返回的提示页
+	reIllegalAccess = regexp.MustCompile(`<title>[^<]*` + IllegalAccessMark + `|alert\(['"][^'"]*` + IllegalAccessMark + `|<(p|div|span|td)[^>]*>\s*` + IllegalAccessMark)
+)
+
+// DefaultSessionValidator 默认的会话校验
+// 依次检查最终地址、未跟随的重定向、重定向链、登录页特征和"非法访问"页面，
+// 只在页面标题或表单这类结构位置匹配，避免导航链接里的"用户登录"字样误判
+type DefaultSessionValidator struct {
+	LoginHosts []string // 统一认证主机，最终地址或重定向指向这些主机即视为失效（SSO 续期除外）
+}
+
+// NewDefaultSessionValidator 创建默认会话校验器
+func NewDefaultSessionValidator() *DefaultSessionValidator {
+	return &DefaultSessionValidator{LoginHosts: []string{casHost}}
+}
+
+// Validate 实现 SessionValidator 接口
+func (v *DefaultSessionValidator) Validate(resp *http.Response, prefix []byte) SessionStatus {
+	// 1. 最终地址落在统一认证
+	if resp.Request != nil && v.isLoginHost(resp.Request.URL) {
+		return SessionExpired
+	}
+
+	// 2. 未跟随的 3xx 指向统一认证
+	if loc, err := resp.Location(); err == nil && v.isLoginHost(loc) {
+		return SessionExpired
+	}
+
+	// 3. 重定向链经过统一认证（例如 ids 又跳回了教务系统的错误页）
+	// 离开统一认证的那一跳带有 ticket 说明是 SSO 续期，会话正常
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		from := req.Response.Request // 发出这次重定向的请求
+		if from != nil && v.isLoginHost(from.URL) && !v.isLoginHost(req.URL) && req.URL.Query().Get("ticket") == "" {
+			return SessionExpired
+		}
+	}
+
+	// 4. 页面结构特征
+	if reLoginTitle.Match(prefix) || reLoginForm.Match(prefix) {
+		return SessionExpired
+	}
+	if reIllegalAccess.Match(prefix) {
+		return SessionNoPermission
+	}
+	return SessionValid
+}
+
+func (v *DefaultSessionValidator) isLoginHost(u *url.URL) bool {
+	return u != nil && slices.Contains(v.LoginHosts, u.Hostname())
+}
+
+// inspectSession 预读响应体前缀并交给 SessionValidator 判断会话状态
+// resp.Body 会被替换为带缓冲的读取器，不影响后续读取
+func (c *Client) inspectSession(resp *http.Response) (SessionStatus, error) {
+	br := bufio.NewReaderSize(resp.Body, sessionCheckPrefix)
+	resp.Body = &bufferedBody{Reader: br, Closer: resp.Body}
+
+	prefix, err := br.Peek(sessionCheckPrefix)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return SessionValid, err
+	}
+	return c.options.sessionValidator.Validate(resp, prefix), nil
+}
+
+// bufferedBody 保留原响应体的 Close，读取走缓冲区
+type bufferedBody struct {
+	*bufio.Reader
+	io.Closer
+}
@@ -0,0 +1,234 @@
+package cas
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const jwglQueryURL = "http://zhjw.qfnu.edu.cn/jsxsd/kbxx/jsjy_query2"
+
+// newResponse 构造最终请求地址为 finalURL 的响应，via 为依次经过的重定向地址
+func newResponse(t *testing.T, status int, finalURL string, location string, via ...string) *http.Response {
+	t.Helper()
+	req := &http.Request{Method: http.MethodPost, URL: mustParse(t, finalURL)}
+	resp := &http.Response{StatusCode: status, Header: make(http.Header), Request: req}
+	if location != "" {
+		resp.Header.Set("Location", location)
+	}
+
+	// 按照 http.Client 跟随重定向的方式串起请求链：每个请求的 Response 是导致它的那个 3xx
+	for i := len(via) - 1; i >= 0; i-- {
+		prevReq := &http.Request{Method: http.MethodGet, URL: mustParse(t, via[i])}
+		redirect := &http.Response{StatusCode: http.StatusFound, Header: make(http.Header), Request: prevReq}
+		redirect.Header.Set("Location", req.URL.String())
+		req.Response = redirect
+		req = prevReq
+	}
+	return resp
+}
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("解析地址 %q 失败：%v", raw, err)
+	}
+	return u
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("读取 %s 失败：%v", name, err)
+	}
+	return body
+}
+
+func TestDefaultSessionValidator(t *testing.T) {
+	const idsLogin = "https://ids.qfnu.edu.cn/authserver/login?service=http%3A%2F%2Fzhjw.qfnu.edu.cn%2Fsso.jsp"
+
+	tests := []struct {
+		name string
+		resp func(t *testing.T) *http.Response
+		body string // testdata 下的文件，为空表示空响应体
+		want SessionStatus
+	}{
+		{
+			name: "未跟随的重定向指向统一认证",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusFound, jwglQueryURL, idsLogin)
+			},
+			want: SessionExpired,
+		},
+		{
+			name: "最终地址落在统一认证",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, idsLogin, "", jwglQueryURL)
+			},
+			body: "ids_login.html",
+			want: SessionExpired,
+		},
+		{
+			name: "统一认证登录页",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, jwglQueryURL, "")
+			},
+			body: "ids_login.html",
+			want: SessionExpired,
+		},
+		{
+			name: "教务系统登录页标题",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, jwglQueryURL, "")
+			},
+			body: "jwgl_login.html",
+			want: SessionExpired,
+		},
+		{
+			name: "教务系统登录表单",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, jwglQueryURL, "")
+			},
+			body: "jwgl_login_form.html",
+			want: SessionExpired,
+		},
+		{
+			name: "非法访问",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, jwglQueryURL, "")
+			},
+			body: "illegal_access.html",
+			want: SessionNoPermission,
+		},
+		{
+			name: "导航栏含用户登录的正常页面",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, jwglQueryURL, "")
+			},
+			body: "jsjy_query.html",
+			want: SessionValid,
+		},
+		{
+			name: "经统一认证续期后回到教务系统",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, jwglQueryURL, "",
+					jwglQueryURL, idsLogin, "http://zhjw.qfnu.edu.cn/sso.jsp?ticket=ST-1-abc")
+			},
+			body: "jsjy_query.html",
+			want: SessionValid,
+		},
+		{
+			name: "经统一认证跳回教务系统但没有 ticket",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, jwglQueryURL, "",
+					jwglQueryURL, idsLogin)
+			},
+			body: "jsjy_query.html",
+			want: SessionExpired,
+		},
+		{
+			name: "统一认证内部跳转后续期",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusOK, jwglQueryURL, "",
+					jwglQueryURL, idsLogin, "https://ids.qfnu.edu.cn/authserver/index.do", "http://zhjw.qfnu.edu.cn/sso.jsp?ticket=ST-2-def")
+			},
+			body: "jsjy_query.html",
+			want: SessionValid,
+		},
+		{
+			name: "教务系统内部重定向",
+			resp: func(t *testing.T) *http.Response {
+				return newResponse(t, http.StatusFound, jwglQueryURL, "/jsxsd/framework/xsMain.jsp")
+			},
+			want: SessionValid,
+		},
+	}
+
+	v := NewDefaultSessionValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			if tt.body != "" {
+				body = readTestdata(t, tt.body)
+			}
+			if got := v.Validate(tt.resp(t), body); got != tt.want {
+				t.Errorf("Validate() = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// largeQueryBody 构造与全天 jsjy_query2 响应体量相当的页面（约 500KB）
+func largeQueryBody(b *testing.B) []byte {
+	b.Helper()
+	page, err := os.ReadFile(filepath.Join("testdata", "jsjy_query.html"))
+	if err != nil {
+		b.Fatalf("读取 jsjy_query.html 失败：%v", err)
+	}
+	row := []byte(`<tr><td>老文史楼101(75/30)</td><td>&nbsp;</td><td>◆</td><td>&nbsp;</td><td>Ｊ</td><td>&nbsp;</td><td>&nbsp;</td></tr>` + "\n")
+	split := bytes.Index(page, []byte("</tbody>"))
+	body := append([]byte{}, page[:split]...)
+	for len(body) < 500<<10 {
+		body = append(body, row...)
+	}
+	return append(body, page[split:]...)
+}
+
+// BenchmarkInspectSession 对比整体读取响应体与只预读前 sessionCheckPrefix 字节两种方式，
+// 两种方式最后都把响应体完整读出，与业务代码的用法一致
+func BenchmarkInspectSession(b *testing.B) {
+	body := largeQueryBody(b)
+	validator := NewDefaultSessionValidator()
+	newResp := func() *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Request:    &http.Request{Method: http.MethodPost, URL: &url.URL{Scheme: "http", Host: "zhjw.qfnu.edu.cn", Path: "/jsxsd/kbxx/jsjy_query2"}},
+		}
+	}
+
+	b.Run("ReadAll", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		for b.Loop() {
+			resp := newResp()
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if validator.Validate(resp, data) != SessionValid {
+				b.Fatal("会话状态应为有效")
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(data))
+			if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Peek", func(b *testing.B) {
+		c := &Client{options: &clientOptions{sessionValidator: validator}}
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		for b.Loop() {
+			resp := newResp()
+			status, err := c.inspectSession(resp)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if status != SessionValid {
+				b.Fatal("会话状态应为有效")
+			}
+			if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// 日志输出目标
+const (
+	OutputConsole = "console"
+	OutputFile    = "file"
+	OutputNone    = "none"
+)
+
+// Format 日志格式
+type Format string
+
+const (
+	FormatGeek   Format = "geek"   // 带颜色的单行格式，适合控制台
+	FormatJSON   Format = "json"   // JSON，每行一条
+	FormatLogfmt Format = "logfmt" // key=value 格式
+)
+
+// ParseFormat 解析日志格式名称，空字符串返回 def
+func ParseFormat(s string, def Format) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return def, nil
+	case FormatGeek, FormatJSON, FormatLogfmt:
+		return f, nil
+	}
+	return def, fmt.Errorf("无效的日志格式 %q", s)
+}
+
+// Config 日志配置
+type Config struct {
+	// Outputs 输出目标：console、file，或 none 表示不输出；为空等同于 console
+	Outputs []string
+
+	ConsoleLevel  slog.Level
+	ConsoleFormat Format    // 默认 geek
+	NoColor       bool      // 关闭控制台颜色（仅 geek 格式）
+	ConsoleWriter io.Writer // 默认 os.Stdout
+
+	FileLevel  slog.Level
+	FileFormat Format // 默认 json
+	Rotation   RotatorOptions
+}
+
+// DefaultConfig 默认配置：只输出到控制台，不创建任何文件
+// 作为库使用（例如只引入 pkg/cas）时即为此配置
+func DefaultConfig() Config {
+	return Config{
+		Outputs:       []string{OutputConsole},
+		ConsoleLevel:  slog.LevelInfo,
+		ConsoleFormat: FormatGeek,
+		NoColor:       !colorEnabled(os.Stdout),
+		FileLevel:     slog.LevelDebug,
+		FileFormat:    FormatJSON,
+		Rotation: RotatorOptions{
+			Dir:        "logs",
+			Prefix:     "app",
+			MaxSizeMB:  10,
+			MaxAgeDays: 14,
+			MaxFiles:   30,
+			Compress:   true,
+		},
+	}
+}
+
+// rotator 当前使用的文件输出，重新 Setup 或 Close 时关闭
+var rotator *LogRotator
+
+// Setup 按配置重建 DefaultLogger 并设为 slog 的默认 Logger
+// 应在程序启动时、记录日志之前调用；未调用时使用 DefaultConfig
+func Setup(cfg Config) error {
+	var handlers []slog.Handler
+	var newRotator *LogRotator
+	for _, out := range cfg.Outputs {
+		switch strings.ToLower(strings.TrimSpace(out)) {
+		case OutputConsole, "":
+			w := cfg.ConsoleWriter
+			if w == nil {
+				w = os.Stdout
+			}
+			h, err := newHandler(w, cfg.ConsoleFormat, FormatGeek, cfg.ConsoleLevel, cfg.NoColor)
+			if err != nil {
+				return err
+			}
+			handlers = append(handlers, h)
+		case OutputFile:
+			// 目录由 NewLogRotatorWithOptions 自动创建
+			newRotator = NewLogRotatorWithOptions(cfg.Rotation)
+			h, err := newHandler(newRotator, cfg.FileFormat, FormatJSON, cfg.FileLevel, true)
+			if err != nil {
+				return err
+			}
+			handlers = append(handlers, h)
+		case OutputNone:
+		default:
+			return fmt.Errorf("无效的日志输出 %q", out)
+		}
+	}
+
+	var handler slog.Handler = slog.DiscardHandler
+	if len(handlers) > 0 {
+		// 组合处理器，并从 context 中提取请求 ID
+		handler = NewContextHandler(NewFanoutHandler(handlers...))
+	}
+
+	old := rotator
+	rotator = newRotator
+	DefaultLogger = slog.New(handler)
+	slog.SetDefault(DefaultLogger)
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Close 关闭文件输出，等待旧日志的压缩和清理完成，程序退出前调用
+func Close() error {
+	if rotator == nil {
+		return nil
+	}
+	return rotator.Close()
+}
+
+// newHandler 按格式创建处理器
+func newHandler(w io.Writer, format, def Format, level slog.Level, noColor bool) (slog.Handler, error) {
+	if format == "" {
+		format = def
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevel}
+	switch format {
+	case FormatGeek:
+		return NewGeekHandler(w, &GeekHandlerOptions{Level: level, NoColor: noColor}), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	case FormatLogfmt:
+		return slog.NewTextHandler(w, opts), nil
+	}
+	return nil, fmt.Errorf("无效的日志格式 %q", format)
+}
@@ -1,11 +1,16 @@
 package logger
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // ANSI 颜色代码
@@ -20,23 +25,45 @@ const (
 	ColorGray   = "\033[37m"
 )
 
+// GeekHandlerOptions GeekHandler 的配置
+type GeekHandlerOptions struct {
+	// Level 最低输出级别，为 nil 时使用 slog.LevelInfo
+	Level slog.Leveler
+	// NoColor 关闭 ANSI 颜色，输出到文件或管道时使用
+	NoColor bool
+}
+
 type GeekHandler struct {
-	w  io.Writer
-	mu sync.Mutex
+	w     io.Writer
+	mu    *sync.Mutex // 由 WithAttrs/WithGroup 派生的处理器共享，保证同一输出不交错
+	level slog.Leveler
+	color bool
+
+	attrs  string   // WithAttrs 预先格式化好的属性，形如 " k=v a.b=c"
+	groups []string // WithGroup 打开的分组，作为后续属性的键前缀
 }
 
-func NewGeekHandler(w io.Writer) *GeekHandler {
-	return &GeekHandler{w: w}
+func NewGeekHandler(w io.Writer, opts *GeekHandlerOptions) *GeekHandler {
+	if opts == nil {
+		opts = &GeekHandlerOptions{}
+	}
+	level := opts.Level
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &GeekHandler{
+		w:     w,
+		mu:    &sync.Mutex{},
+		level: level,
+		color: !opts.NoColor,
+	}
 }
 
 func (h *GeekHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return true
+	return level >= h.level.Level()
 }
 
 func (h *GeekHandler) Handle(ctx context.Context, r slog.Record) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
 	var prefix, color string
 
 	switch r.Level {
@@ -52,7 +79,7 @@ func (h *GeekHandler) Handle(ctx context.Context, r slog.Record) error {
 	case slog.LevelError:
 		prefix = "[ERROR]"
 		color = ColorRed
-	case slog.Level(12): // 自定义 Fatal 级别
+	case LevelFatal:
 		prefix = "[FATAL]"
 		color = ColorPurple
 	default:
@@ -65,31 +92,108 @@ func (h *GeekHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	// 主日志消息
 	// 格式：[颜色]时间 [级别] >> 消息[重置颜色]
-	msg := fmt.Sprintf("%s%s %s >> %s%s", color, timeStr, prefix, r.Message, ColorReset)
+	var buf bytes.Buffer
+	buf.WriteString(h.paint(color, fmt.Sprintf("%s %s >> %s", timeStr, prefix, r.Message)))
 
-	// 处理属性（如果有）
+	// 处理属性（WithAttrs 预设的 + 本条记录的）
 	// 我们将它们作为 key=value 对附加在消息后面，使用灰色显示
+	attrs := h.attrs
 	if r.NumAttrs() > 0 {
-		msg += fmt.Sprintf(" %s{", ColorGray)
+		var ab bytes.Buffer
+		prefix := groupPrefix(h.groups)
 		r.Attrs(func(a slog.Attr) bool {
-			msg += fmt.Sprintf(" %s=%v", a.Key, a.Value.Any())
+			appendAttr(&ab, prefix, a)
 			return true
 		})
-		msg += fmt.Sprintf(" }%s", ColorReset)
+		attrs += ab.String()
+	}
+	if attrs != "" {
+		buf.WriteString(" ")
+		buf.WriteString(h.paint(ColorGray, "{"+attrs+" }"))
 	}
+	buf.WriteByte('\n')
 
-	_, err := fmt.Fprintln(h.w, msg)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
 	return err
 }
 
 func (h *GeekHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	// 为了简化这个极客实现，我们返回相同的处理器
-	// 在完整的实现中，我们会复制处理器并添加预格式化的属性
-	return h
+	if len(attrs) == 0 {
+		return h
+	}
+	var buf bytes.Buffer
+	prefix := groupPrefix(h.groups)
+	for _, a := range attrs {
+		appendAttr(&buf, prefix, a)
+	}
+	h2 := *h
+	h2.attrs = h.attrs + buf.String()
+	return &h2
 }
 
 func (h *GeekHandler) WithGroup(name string) slog.Handler {
-	return h
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(slices.Clip(h.groups), name)
+	return &h2
+}
+
+// paint 在启用颜色时为文本加上颜色
+func (h *GeekHandler) paint(color, s string) string {
+	if !h.color {
+		return s
+	}
+	return color + s + ColorReset
+}
+
+func groupPrefix(groups []string) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	return strings.Join(groups, ".") + "."
+}
+
+// appendAttr 将属性格式化为 " key=value"，分组属性展开为 " group.key=value"
+func appendAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		// 与 slog 的约定一致：没有键的分组直接内联
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendAttr(buf, prefix, ga)
+		}
+		return
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(prefix)
+	buf.WriteString(a.Key)
+	buf.WriteByte('=')
+	buf.WriteString(formatValue(a.Value))
+}
+
+// formatValue 格式化属性值，含空白或引号的字符串加引号，避免与相邻属性混淆
+func formatValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindString:
+		s := v.String()
+		if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+			return strconv.Quote(s)
+		}
+		return s
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", v.Any())
+	}
 }
 
 // FanoutHandler 将日志广播给多个处理器
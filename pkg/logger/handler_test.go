@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newTestLogger 创建输出到 buf 的 Logger，与 Setup 一样外面包一层 ContextHandler
+func newTestLogger(t *testing.T, buf *bytes.Buffer, format Format, noColor bool) *slog.Logger {
+	t.Helper()
+	h, err := newHandler(buf, format, FormatGeek, slog.LevelDebug, noColor)
+	if err != nil {
+		t.Fatalf("创建 %s 处理器失败：%v", format, err)
+	}
+	return slog.New(NewContextHandler(h))
+}
+
+// TestHandlerFormats 同一条记录在三种格式下都应包含级别、消息、属性、分组和请求 ID
+func TestHandlerFormats(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   []string // 输出中应出现的片段
+	}{
+		{
+			format: FormatGeek,
+			want: []string{
+				"[WARN ] >> 重新登录",
+				" component=cas",
+				" request_id=req-42",
+				` reason="session expired"`,
+				" login.user=2021",
+				" login.attempt=2",
+			},
+		},
+		{
+			format: FormatLogfmt,
+			want: []string{
+				"level=WARN",
+				"msg=重新登录",
+				"component=cas",
+				"request_id=req-42",
+				`reason="session expired"`,
+				"login.user=2021",
+				"login.attempt=2",
+			},
+		},
+	}
+
+	ctx := WithRequestID(context.Background(), "req-42")
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			log := newTestLogger(t, &buf, tt.format, true)
+			log.With("component", "cas").WarnContext(ctx, "重新登录",
+				"reason", "session expired",
+				slog.Group("login", "user", "2021", "attempt", 2))
+
+			out := buf.String()
+			if strings.Count(out, "\n") != 1 {
+				t.Fatalf("应输出一行日志，实际为 %q", out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("输出 %q 中缺少 %q", out, want)
+				}
+			}
+		})
+	}
+
+	t.Run(string(FormatJSON), func(t *testing.T) {
+		var buf bytes.Buffer
+		log := newTestLogger(t, &buf, FormatJSON, true)
+		log.With("component", "cas").WarnContext(ctx, "重新登录",
+			"reason", "session expired",
+			slog.Group("login", "user", "2021", "attempt", 2))
+
+		var got map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("解析 JSON 失败：%v，输出 %q", err, buf.String())
+		}
+		for key, want := range map[string]any{
+			"level":      "WARN",
+			"msg":        "重新登录",
+			"component":  "cas",
+			"request_id": "req-42",
+			"reason":     "session expired",
+		} {
+			if got[key] != want {
+				t.Errorf("%s = %v，期望 %v", key, got[key], want)
+			}
+		}
+		login, _ := got["login"].(map[string]any)
+		if login["user"] != "2021" || login["attempt"] != float64(2) {
+			t.Errorf("login 分组 = %v，期望 user=2021 attempt=2", got["login"])
+		}
+	})
+}
+
+// TestHandlerWithGroup WithGroup 之后的属性都带上分组前缀，之前 With 的属性不受影响
+func TestHandlerWithGroup(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   []string
+	}{
+		{FormatGeek, []string{" component=cas", " http.method=POST", " http.status=200"}},
+		{FormatLogfmt, []string{"component=cas", "http.method=POST", "http.status=200"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			log := newTestLogger(t, &buf, tt.format, true)
+			log.With("component", "cas").WithGroup("http").With("method", "POST").Info("请求完成", "status", 200)
+
+			out := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("输出 %q 中缺少 %q", out, want)
+				}
+			}
+		})
+	}
+}
+
+// TestHandlerWithoutRequestID context 中没有请求 ID 时不输出 request_id
+func TestHandlerWithoutRequestID(t *testing.T) {
+	for _, format := range []Format{FormatGeek, FormatJSON, FormatLogfmt} {
+		var buf bytes.Buffer
+		newTestLogger(t, &buf, format, true).InfoContext(context.Background(), "启动完成")
+		if strings.Contains(buf.String(), "request_id") {
+			t.Errorf("%s 格式不应输出 request_id：%q", format, buf.String())
+		}
+	}
+}
+
+// TestGeekHandlerLevel 低于配置级别的日志不输出
+func TestGeekHandlerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewGeekHandler(&buf, &GeekHandlerOptions{Level: slog.LevelWarn, NoColor: true}))
+	log.Info("忽略")
+	log.Warn("保留")
+
+	out := buf.String()
+	if strings.Contains(out, "忽略") || !strings.Contains(out, "保留") {
+		t.Errorf("级别过滤错误：%q", out)
+	}
+}
+
+func TestGeekHandlerColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		noColor bool
+		level   slog.Level
+		color   string
+	}{
+		{"info", false, slog.LevelInfo, ColorCyan},
+		{"warn", false, slog.LevelWarn, ColorYellow},
+		{"error", false, slog.LevelError, ColorRed},
+		{"no color", true, slog.LevelError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			newTestLogger(t, &buf, FormatGeek, tt.noColor).Log(context.Background(), tt.level, "消息", "k", "v")
+
+			out := buf.String()
+			if tt.color == "" {
+				if strings.Contains(out, "\033[") {
+					t.Errorf("NoColor 时不应包含 ANSI 转义：%q", out)
+				}
+				return
+			}
+			if !strings.HasPrefix(out, tt.color) {
+				t.Errorf("输出 %q 应以颜色 %q 开头", out, tt.color)
+			}
+			if !strings.Contains(out, ColorGray+"{ k=v }"+ColorReset) {
+				t.Errorf("属性应以灰色输出：%q", out)
+			}
+		})
+	}
+}
+
+// TestFatalLevelName 各格式中 Fatal 级别都显示为 FATAL
+func TestFatalLevelName(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatGeek, "[FATAL] >> 退出"},
+		{FormatJSON, `"level":"FATAL"`},
+		{FormatLogfmt, "level=FATAL"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		newTestLogger(t, &buf, tt.format, true).Log(context.Background(), LevelFatal, "退出")
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%s 格式输出 %q 中缺少 %q", tt.format, buf.String(), tt.want)
+		}
+	}
+}
@@ -5,27 +5,60 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 )
 
 var DefaultLogger *slog.Logger
 
+// LevelFatal 自定义的 Fatal 级别，记录后进程退出
+const LevelFatal = slog.Level(12)
+
 func init() {
-	// 1. 控制台处理器（极客风格，带颜色）
-	consoleHandler := NewGeekHandler(os.Stdout)
+	// 默认只输出到控制台，不创建日志文件；服务端在启动时调用 Setup 启用文件输出
+	if err := Setup(DefaultConfig()); err != nil {
+		panic(err)
+	}
+}
 
-	// 2. 文件处理器（JSON 结构化）带自动轮转
-	// 目录由 NewLogRotator 自动创建
-	// 10MB 轮转一次
-	rotator := NewLogRotator("logs", 10)
-	fileHandler := slog.NewJSONHandler(rotator, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
-	})
+// ParseLevel 解析日志级别名称（debug/info/warn/error，不区分大小写），空字符串为 info
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("无效的日志级别 %q", s)
+}
 
-	// 3. 组合处理器
-	finalHandler := NewFanoutHandler(consoleHandler, fileHandler)
+// colorEnabled 判断控制台是否着色
+// LOG_COLOR=always/never 强制开关；默认（auto）仅在输出为终端且未设置 NO_COLOR 时着色
+func colorEnabled(f *os.File) bool {
+	switch strings.ToLower(os.Getenv("LOG_COLOR")) {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
 
-	DefaultLogger = slog.New(finalHandler)
-	slog.SetDefault(DefaultLogger)
+// replaceLevel 让 JSON 日志中的 Fatal 级别显示为 FATAL 而不是 ERROR+4
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.LevelKey {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == LevelFatal {
+			a.Value = slog.StringValue("FATAL")
+		}
+	}
+	return a
 }
 
 func Info(format string, v ...interface{}) {
@@ -67,11 +100,15 @@ func Fatal(format string, v ...interface{}) {
 	// LevelError = 8。我们将 Fatal 设为 12。
 
 	// 实际上，为了简单起见，我们直接使用自定义的 Log 调用
-	DefaultLogger.Log(context.Background(), slog.Level(12), msg)
+	DefaultLogger.Log(context.Background(), LevelFatal, msg)
 	os.Exit(1)
 }
 
 // 支持结构化日志
+func DebugS(msg string, args ...any) {
+	DefaultLogger.Debug(msg, args...)
+}
+
 func InfoS(msg string, args ...any) {
 	DefaultLogger.Info(msg, args...)
 }
@@ -83,3 +120,20 @@ func WarnS(msg string, args ...any) {
 func ErrorS(msg string, args ...any) {
 	DefaultLogger.Error(msg, args...)
 }
+
+// 带 context 的结构化日志，context 中的请求 ID 会一并输出
+func DebugCtx(ctx context.Context, msg string, args ...any) {
+	DefaultLogger.DebugContext(ctx, msg, args...)
+}
+
+func InfoCtx(ctx context.Context, msg string, args ...any) {
+	DefaultLogger.InfoContext(ctx, msg, args...)
+}
+
+func WarnCtx(ctx context.Context, msg string, args ...any) {
+	DefaultLogger.WarnContext(ctx, msg, args...)
+}
+
+func ErrorCtx(ctx context.Context, msg string, args ...any) {
+	DefaultLogger.ErrorContext(ctx, msg, args...)
+}
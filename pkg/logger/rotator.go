@@ -1,94 +1,145 @@
 package logger
 
 import (
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// RotatorOptions 日志轮转配置
+type RotatorOptions struct {
+	Dir        string // 日志存储目录
+	Prefix     string // 文件名前缀，文件名格式为 <prefix>-YYYY-MM-DD[_seq].log
+	MaxSizeMB  int    // 单个日志文件的最大大小（MB），<= 0 表示不按大小轮转
+	MaxAgeDays int    // 轮转后的文件保留天数，<= 0 表示不按时间清理
+	MaxFiles   int    // 最多保留的轮转文件数（不含当前文件），<= 0 表示不限制
+	Compress   bool   // 是否将轮转后的文件压缩为 .gz
+}
+
 // LogRotator 实现了 io.Writer 接口，用于处理日志文件的自动轮转
+// 每天零点以及文件超过大小上限时轮转，轮转后的旧文件在后台压缩和清理
 type LogRotator struct {
-	mu        sync.Mutex
-	dir       string
-	prefix    string
-	ext       string
-	file      *os.File
-	size      int64
-	maxSize   int64 // 字节
-	startTime time.Time
-	seq       int
+	mu      sync.Mutex
+	opts    RotatorOptions
+	ext     string
+	file    *os.File
+	path    string
+	size    int64
+	maxSize int64  // 字节
+	day     string // 当前文件对应的日期，YYYY-MM-DD
+	seq     int
+	closed  bool // Close 之后不再打开文件，写入返回 os.ErrClosed
+	now     func() time.Time
+
+	millMu sync.Mutex // 保证压缩和清理串行执行
+	millWG sync.WaitGroup
 }
 
 // NewLogRotator 创建一个新的 LogRotator
 // dir: 日志存储目录
 // maxSizeMB: 单个日志文件的最大大小（MB），0 表示不限制
 func NewLogRotator(dir string, maxSizeMB int) *LogRotator {
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	return NewLogRotatorWithOptions(RotatorOptions{Dir: dir, MaxSizeMB: maxSizeMB})
+}
+
+// NewLogRotatorWithOptions 按配置创建 LogRotator
+func NewLogRotatorWithOptions(opts RotatorOptions) *LogRotator {
+	if opts.Dir == "" {
+		opts.Dir = "logs"
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "app"
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
 		fmt.Fprintf(os.Stderr, "创建日志目录失败：%v\n", err)
 	}
 	return &LogRotator{
-		dir:       dir,
-		prefix:    "app",
-		ext:       ".log",
-		maxSize:   int64(maxSizeMB) * 1024 * 1024,
-		startTime: time.Now(),
-		seq:       0,
+		opts:    opts,
+		ext:     ".log",
+		maxSize: int64(opts.MaxSizeMB) * 1024 * 1024,
+		now:     time.Now,
 	}
 }
 
-func (r *LogRotator) openNewFile() error {
+func (r *LogRotator) filename(day string, seq int) string {
+	if seq == 0 {
+		return fmt.Sprintf("%s-%s%s", r.opts.Prefix, day, r.ext)
+	}
+	return fmt.Sprintf("%s-%s_%d%s", r.opts.Prefix, day, seq, r.ext)
+}
+
+// openFile 打开 day 当天的日志文件，已存在且已满（或已被压缩）时顺延序号
+func (r *LogRotator) openFile(day string, seq int) error {
 	// 关闭现有文件（如果存在）
+	old := r.path
 	if r.file != nil {
 		r.file.Close()
+		r.file = nil
 	}
 
-	// 格式：app-YYYY-MM-DD_HH-mm-ss[_seq].log
-	timestamp := r.startTime.Format("2006-01-02_15-04-05")
-	var filename string
-	if r.seq == 0 {
-		filename = fmt.Sprintf("%s-%s%s", r.prefix, timestamp, r.ext)
-	} else {
-		filename = fmt.Sprintf("%s-%s_%d%s", r.prefix, timestamp, r.seq, r.ext)
-	}
-
-	path := filepath.Join(r.dir, filename)
+	for ; ; seq++ {
+		path := filepath.Join(r.opts.Dir, r.filename(day, seq))
+		if _, err := os.Stat(path + ".gz"); err == nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil && r.maxSize > 0 && info.Size() >= r.maxSize {
+			continue
+		}
 
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		return err
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return err
+		}
+		r.file = f
+		r.path = path
+		r.day = day
+		r.seq = seq
+		r.size = 0
+		// 以追加模式打开现有文件（例如当天重启）时，从已有大小继续计算
+		if info, err := f.Stat(); err == nil {
+			r.size = info.Size()
+		}
+		break
 	}
 
-	r.file = f
-	r.size = 0
-
-	// 如果是以追加模式打开现有文件（例如同一秒内重启），检查文件大小
-	info, err := f.Stat()
-	if err == nil {
-		r.size = info.Size()
+	// 首次打开时也整理一次，处理上次运行遗留的文件
+	if old != r.path {
+		r.millWG.Add(1)
+		go r.mill()
 	}
-
 	return nil
 }
 
 func (r *LogRotator) Write(p []byte) (n int, err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-
-	// 首次写入时打开文件
-	if r.file == nil {
-		if err := r.openNewFile(); err != nil {
-			return 0, err
-		}
+	if r.closed {
+		// 重新 Setup 后仍持有旧处理器的日志调用，不应重新打开文件并启动清理
+		return 0, os.ErrClosed
 	}
 
-	// 检查是否需要轮转
-	if r.maxSize > 0 && r.size+int64(len(p)) > r.maxSize {
-		r.seq++
-		if err := r.openNewFile(); err != nil {
-			return 0, err
-		}
+	today := r.now().Format("2006-01-02")
+	switch {
+	case r.file == nil:
+		// 首次写入时打开文件，接着当天已有的最大序号继续写
+		err = r.openFile(today, r.lastSeq(today))
+	case today != r.day:
+		// 跨天轮转
+		err = r.openFile(today, 0)
+	case r.maxSize > 0 && r.size+int64(len(p)) > r.maxSize:
+		// 检查是否需要按大小轮转
+		err = r.openFile(today, r.seq+1)
+	}
+	if err != nil {
+		return 0, err
 	}
 
 	n, err = r.file.Write(p)
@@ -96,11 +147,161 @@ func (r *LogRotator) Write(p []byte) (n int, err error) {
 	return n, err
 }
 
+// lastSeq 返回目录中 day 当天日志文件的最大序号
+func (r *LogRotator) lastSeq(day string) int {
+	entries, err := os.ReadDir(r.opts.Dir)
+	if err != nil {
+		return 0
+	}
+	base := r.opts.Prefix + "-" + day
+	last := 0
+	for _, e := range entries {
+		name := strings.TrimSuffix(strings.TrimSuffix(e.Name(), ".gz"), r.ext)
+		rest, ok := strings.CutPrefix(name, base+"_")
+		if !ok {
+			continue
+		}
+		if seq, err := strconv.Atoi(rest); err == nil && seq > last {
+			last = seq
+		}
+	}
+	return last
+}
+
+// Close 关闭当前文件，并等待后台的压缩和清理完成；之后的 Write 返回 os.ErrClosed
 func (r *LogRotator) Close() error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	r.closed = true
+	var err error
 	if r.file != nil {
-		return r.file.Close()
+		err = r.file.Close()
+		r.file = nil
 	}
-	return nil
+	r.mu.Unlock()
+	r.millWG.Wait()
+	return err
+}
+
+// rotatedFile 目录中除当前文件外的日志文件
+type rotatedFile struct {
+	path    string
+	modTime time.Time
+}
+
+// mill 压缩除当前文件外的旧日志，并按保留天数和文件数清理
+func (r *LogRotator) mill() {
+	defer r.millWG.Done()
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+
+	// 排队期间可能又轮转过，以最新的当前文件为准
+	r.mu.Lock()
+	current := r.path
+	r.mu.Unlock()
+
+	files, err := r.rotatedFiles(current)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取日志目录失败：%v\n", err)
+		return
+	}
+
+	// 1. 按保留天数和文件数确定要删除的文件（新的在前）
+	var keep, remove []rotatedFile
+	cutoff := r.now().AddDate(0, 0, -r.opts.MaxAgeDays)
+	for _, f := range files {
+		if r.opts.MaxAgeDays > 0 && f.modTime.Before(cutoff) {
+			remove = append(remove, f)
+			continue
+		}
+		if r.opts.MaxFiles > 0 && len(keep) >= r.opts.MaxFiles {
+			remove = append(remove, f)
+			continue
+		}
+		keep = append(keep, f)
+	}
+	for _, f := range remove {
+		if err := os.Remove(f.path); err != nil {
+			fmt.Fprintf(os.Stderr, "删除旧日志失败：%v\n", err)
+		}
+	}
+
+	// 2. 压缩保留下来的未压缩文件
+	if !r.opts.Compress {
+		return
+	}
+	for _, f := range keep {
+		if strings.HasSuffix(f.path, r.ext) {
+			if err := compressFile(f.path); err != nil {
+				fmt.Fprintf(os.Stderr, "压缩日志失败：%v\n", err)
+			}
+		}
+	}
+}
+
+// rotatedFiles 列出属于本 LogRotator 的旧日志文件，按修改时间从新到旧排序
+func (r *LogRotator) rotatedFiles(current string) ([]rotatedFile, error) {
+	entries, err := os.ReadDir(r.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []rotatedFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, r.opts.Prefix+"-") {
+			continue
+		}
+		if !strings.HasSuffix(name, r.ext) && !strings.HasSuffix(name, r.ext+".gz") {
+			continue
+		}
+		path := filepath.Join(r.opts.Dir, name)
+		if path == current {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{path: path, modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	return files, nil
+}
+
+// compressFile 将文件压缩为 path.gz 并删除原文件，保留原修改时间以便按时间清理
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	gzPath := path + ".gz"
+	dst, err := os.OpenFile(gzPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(gzPath)
+		return err
+	}
+
+	os.Chtimes(gzPath, info.ModTime(), info.ModTime())
+	return os.Remove(path)
 }
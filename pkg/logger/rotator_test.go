@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// newTestRotator 创建使用 clock 的 LogRotator，测试结束时关闭
+func newTestRotator(t *testing.T, opts RotatorOptions, clock *fakeClock) *LogRotator {
+	t.Helper()
+	r := NewLogRotatorWithOptions(opts)
+	r.now = clock.Now
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func mustWrite(t *testing.T, r *LogRotator, s string) {
+	t.Helper()
+	if _, err := r.Write([]byte(s)); err != nil {
+		t.Fatalf("写入日志失败：%v", err)
+	}
+}
+
+// touch 在 dir 下创建内容为 name 的文件，并把修改时间设为 mtime
+func touch(t *testing.T, dir, name string, mtime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLogRotatorWriteAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	r := NewLogRotatorWithOptions(RotatorOptions{Dir: dir, Prefix: "test"})
+	if _, err := r.Write([]byte("第一条\n")); err != nil {
+		t.Fatalf("写入失败：%v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("关闭失败：%v", err)
+	}
+
+	n, err := r.Write([]byte("关闭之后\n"))
+	if n != 0 || !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Close 之后 Write = (%d, %v)，期望 (0, os.ErrClosed)", n, err)
+	}
+	if r.file != nil {
+		t.Error("Close 之后不应重新打开日志文件")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("日志目录中应只有一个文件，实际为 %d 个", len(entries))
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("重复关闭失败：%v", err)
+	}
+}
+
+func TestLogRotatorDailyRotation(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 23, 59, 59, 0, time.Local)}
+	r := newTestRotator(t, RotatorOptions{Dir: dir}, clock)
+
+	mustWrite(t, r, "a\n")
+	clock.Set(time.Date(2026, 3, 2, 0, 0, 1, 0, time.Local))
+	mustWrite(t, r, "b\n")
+	mustWrite(t, r, "c\n")
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app-2026-03-01.log", "app-2026-03-02.log"}
+	if got := listDir(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("日志文件 = %v，期望 %v", got, want)
+	}
+	if got := readFile(t, filepath.Join(dir, want[0])); got != "a\n" {
+		t.Errorf("前一天的日志 = %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, want[1])); got != "b\nc\n" {
+		t.Errorf("当天的日志 = %q", got)
+	}
+}
+
+func TestLogRotatorPrune(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		opts RotatorOptions
+		want []string // 保留的文件，含当前文件
+	}{
+		{
+			name: "按保留天数",
+			opts: RotatorOptions{MaxAgeDays: 3},
+			want: []string{"app-2026-03-08.log", "app-2026-03-09.log", "app-2026-03-10.log"},
+		},
+		{
+			name: "按文件数",
+			opts: RotatorOptions{MaxFiles: 1},
+			want: []string{"app-2026-03-09.log", "app-2026-03-10.log"},
+		},
+		{
+			name: "同时限制",
+			opts: RotatorOptions{MaxAgeDays: 30, MaxFiles: 3},
+			want: []string{"app-2026-03-01.log", "app-2026-03-08.log", "app-2026-03-09.log", "app-2026-03-10.log"},
+		},
+		{
+			name: "不限制",
+			opts: RotatorOptions{},
+			want: []string{"app-2026-02-01.log", "app-2026-03-01.log", "app-2026-03-08.log", "app-2026-03-09.log", "app-2026-03-10.log"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			touch(t, dir, "app-2026-02-01.log", now.AddDate(0, 0, -37))
+			touch(t, dir, "app-2026-03-01.log", now.AddDate(0, 0, -9))
+			touch(t, dir, "app-2026-03-08.log", now.AddDate(0, 0, -2))
+			touch(t, dir, "app-2026-03-09.log", now.AddDate(0, 0, -1))
+			// 其他前缀的文件不受影响
+			touch(t, dir, "other-2026-01-01.log", now.AddDate(0, 0, -60))
+
+			opts := tt.opts
+			opts.Dir = dir
+			r := newTestRotator(t, opts, &fakeClock{t: now})
+			mustWrite(t, r, "x\n")
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			want := append(slices.Clone(tt.want), "other-2026-01-01.log")
+			if got := listDir(t, dir); !slices.Equal(got, want) {
+				t.Errorf("日志文件 = %v，期望 %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLogRotatorCompress(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)}
+	r := newTestRotator(t, RotatorOptions{Dir: dir, Compress: true}, clock)
+
+	mustWrite(t, r, "first day\n")
+	clock.Set(time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local))
+	mustWrite(t, r, "second day\n")
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 当前文件不压缩
+	want := []string{"app-2026-03-01.log.gz", "app-2026-03-02.log"}
+	if got := listDir(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("日志文件 = %v，期望 %v", got, want)
+	}
+
+	f, err := os.Open(filepath.Join(dir, want[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("打开压缩文件失败：%v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("解压失败：%v", err)
+	}
+	if string(data) != "first day\n" {
+		t.Errorf("解压后的内容 = %q", data)
+	}
+}
+
+func TestLogRotatorSizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)}
+	r := newTestRotator(t, RotatorOptions{Dir: dir, MaxSizeMB: 1}, clock)
+
+	chunk := make([]byte, 600*1024)
+	for i := range 3 {
+		if _, err := r.Write(chunk); err != nil {
+			t.Fatalf("第 %d 次写入失败：%v", i+1, err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app-2026-03-01.log", "app-2026-03-01_1.log", "app-2026-03-01_2.log"}
+	if got := listDir(t, dir); !slices.Equal(got, want) {
+		t.Errorf("日志文件 = %v，期望 %v", got, want)
+	}
+}
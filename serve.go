@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/api/admin"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/api/health"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/api/middleware"
+	v1 "github.com/W1ndys/easy-qfnu-empty-classrooms/internal/api/v1"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/app"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/config"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/metrics"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/service"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/store"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/web"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// upstreamProbeInterval 探测教务系统可达性的最小间隔
+const upstreamProbeInterval = 10 * time.Second
+
+// newHealthHandler 注册就绪检查项：启动完成、已登录、日历已加载、教务系统可达、未熔断
+func newHealthHandler(cfg *config.Config, application *app.App, startup *service.Startup, client *cas.Client) *health.Handler {
+	h := health.NewHandler(time.Duration(cfg.Server.ReadyTimeout))
+	h.AddCheck("startup", func(context.Context) error {
+		if state := startup.State(); state.Phase != service.StartupReady {
+			if state.LastError != "" {
+				return fmt.Errorf("%s（%s）", state.Message, state.LastError)
+			}
+			return errors.New(state.Message)
+		}
+		if !application.Ready() {
+			return errors.New("服务正在退出")
+		}
+		return nil
+	})
+	h.AddCheck("login", func(context.Context) error {
+		state := client.SessionState()
+		if !state.LoggedIn {
+			if state.LastFailure != "" {
+				return fmt.Errorf("未登录：%s", state.LastFailure)
+			}
+			return errors.New("未登录")
+		}
+		return nil
+	})
+	h.AddCheck("calendar", func(context.Context) error {
+		cal := service.GetCalendarService()
+		if cal == nil || cal.LoadedAt().IsZero() {
+			return errors.New("日历未加载")
+		}
+		return nil
+	})
+	h.AddCheck("upstream", health.Cached(client.Probe, upstreamProbeInterval))
+	h.AddCheck("circuit", func(context.Context) error {
+		var open []string
+		for host, st := range client.BreakerStates() {
+			if st == cas.BreakerOpen {
+				open = append(open, host)
+			}
+		}
+		if len(open) > 0 {
+			sort.Strings(open)
+			return fmt.Errorf("已熔断：%s", strings.Join(open, ", "))
+		}
+		return nil
+	})
+	return h
+}
+
+// runServe 启动 Web 服务
+func runServe(args []string) error {
+	var printConfig bool
+	cfg, _ := parseConfig("serve", args, false, func(fs *flag.FlagSet) {
+		fs.BoolVar(&printConfig, "print-config", false, "打印生效的配置（隐去密码和令牌）后退出")
+	})
+	// 只打印配置时不初始化日志，避免创建日志目录
+	if printConfig {
+		return cfg.Print(os.Stdout)
+	}
+	setupLogger(cfg, false)
+	// 正常退出时由退出步骤关闭日志，这里处理启动前就返回的情况，重复关闭没有副作用
+	defer logger.Close()
+
+	application := app.New(app.WithShutdownTimeout(time.Duration(cfg.Server.ShutdownTimeout)))
+	// 最先注册、最后执行，保证退出过程中的日志都能写入文件
+	application.OnShutdown("关闭日志", func(context.Context) error { return logger.Close() })
+
+	// 设置 Gin 模式
+	gin.SetMode(cfg.Server.Mode)
+
+	// 1. 初始化 CAS 客户端
+	client, manualSolver, err := newClient(cfg, false)
+	if err != nil {
+		return err
+	}
+	// 账号密码也可以在启动后通过管理接口设置
+	client.SetCredentials(cfg.CAS.Username, cfg.CAS.Password)
+
+	// 2. 初始化服务
+	// 登录和日历初始化在后台进行并自动重试，HTTP 服务立即启动，
+	// 完成之前 /api/v1/status 说明当前阶段，查询接口返回 503
+	serviceCfg := serviceConfig(cfg.Service)
+	startup := service.NewStartup(client, serviceCfg, loginTimeout(cfg), func() { application.SetReady(true) })
+	application.OnInit("启动后台登录", func(context.Context) error {
+		application.Go("登录和初始化日历", startup.Run)
+		return nil
+	})
+
+	var serviceOpts []service.Option
+	if cfg.Store.Dir != "" {
+		snapshots, err := store.Open(cfg.Store.Dir)
+		if err != nil {
+			return err
+		}
+		serviceOpts = append(serviceOpts, service.WithSnapshotStore(snapshots))
+	}
+	classroomService := service.NewClassroomService(client, serviceCfg, serviceOpts...)
+	apiHandler := v1.NewHandler(classroomService, startup)
+	adminHandler := admin.NewHandler(client, classroomService, manualSolver, startup)
+	healthHandler := newHealthHandler(cfg, application, startup, client)
+
+	// 3. 设置 Gin
+	// 不使用 gin.Default 的访问日志，改为带请求 ID 的结构化日志
+	r := gin.New()
+	r.Use(gin.Recovery(), middleware.RequestID(), middleware.AccessLog("/healthz", "/readyz"), metrics.Middleware())
+	// 禁用 Gin 的自动重定向行为，防止 index.html 路径与 / 路径发生死循环
+	r.RedirectTrailingSlash = false
+	r.RedirectFixedPath = false
+
+	// 静态文件服务 (Embed)
+	// web.StaticFS 根目录下就是 index.html 和 css/
+	r.StaticFS("/static", http.FS(web.StaticFS))
+
+	// 根路径返回 index.html (显式读取模式)
+	// 使用 ReadFile 显式加载并返回，避免 FileFromFS 可能触发的路径重定向问题
+	r.GET("/", func(c *gin.Context) {
+		content, err := web.StaticFS.ReadFile("index.html")
+		if err != nil {
+			c.String(http.StatusInternalServerError, "无法加载 index.html")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", content)
+	})
+
+	// 显式添加其他 HTML 页面的路由
+	r.GET("/empty-classroom", func(c *gin.Context) {
+		content, err := web.StaticFS.ReadFile("empty-classroom.html")
+		if err != nil {
+			c.String(http.StatusNotFound, "404 Not Found")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", content)
+	})
+
+	r.GET("/full-day-status", func(c *gin.Context) {
+		content, err := web.StaticFS.ReadFile("full-day-status.html")
+		if err != nil {
+			c.String(http.StatusNotFound, "404 Not Found")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", content)
+	})
+
+	r.GET("/admin/captcha", func(c *gin.Context) {
+		content, err := web.StaticFS.ReadFile("admin-captcha.html")
+		if err != nil {
+			c.String(http.StatusNotFound, "404 Not Found")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", content)
+	})
+
+	// API 路由
+	api := r.Group("/api/v1")
+	{
+		api.GET("/status", apiHandler.GetStatus)
+		api.POST("/query", apiHandler.RequireReady(), apiHandler.QueryClassrooms)
+		api.POST("/query-full-day", apiHandler.RequireReady(), apiHandler.QueryFullDayStatus)
+		api.POST("/search", apiHandler.RequireReady(), apiHandler.SearchRooms)
+		api.GET("/campuses", apiHandler.ListCampuses)
+		// 只读取本地快照，不需要等待登录完成
+		api.GET("/analytics/occupancy", apiHandler.OccupancyAnalytics)
+		api.GET("/rooms/:room", apiHandler.RequireReady(), apiHandler.RoomSchedule)
+		api.GET("/rooms/:room/calendar.ics", apiHandler.RequireReady(), apiHandler.RoomCalendar)
+	}
+
+	// 存活和就绪探针
+	r.GET("/healthz", healthHandler.Healthz)
+	r.GET("/readyz", healthHandler.Readyz)
+
+	// Prometheus 指标
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// 管理接口，需通过 ADMIN_TOKEN 鉴权
+	adminAPI := r.Group("/api/admin", admin.TokenAuth(cfg.Admin.Token))
+	{
+		adminAPI.GET("/captcha", adminHandler.GetCaptcha)
+		adminAPI.POST("/captcha", adminHandler.SubmitCaptcha)
+		adminAPI.GET("/session", adminHandler.GetSession)
+		adminAPI.POST("/login", adminHandler.Login)
+		adminAPI.POST("/calendar/refresh", adminHandler.RefreshCalendar)
+		adminAPI.PUT("/credentials", adminHandler.UpdateCredentials)
+		adminAPI.POST("/cache/flush", adminHandler.FlushCache)
+	}
+
+	// 启动，收到 SIGINT/SIGTERM 后停止接收新请求，等待进行中的请求完成再退出
+	application.Serve(fmt.Sprintf(":%d", cfg.Server.Port), r)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return application.Run(ctx)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/config"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/metrics"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/internal/service"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-empty-classrooms/pkg/logger"
+)
+
+// loadConfig 加载配置并初始化日志
+// register 用于注册子命令自己的参数；cli 为 true 时日志只输出到 stderr 且默认级别为 warn，避免干扰查询结果
+func loadConfig(name string, args []string, cli bool, register func(fs *flag.FlagSet)) (*config.Config, *flag.FlagSet) {
+	cfg, fs := parseConfig(name, args, cli, register)
+	setupLogger(cfg, cli)
+	return cfg, fs
+}
+
+// parseConfig 只加载配置，不初始化日志，参数含义同 loadConfig
+func parseConfig(name string, args []string, cli bool, register func(fs *flag.FlagSet)) (*config.Config, *flag.FlagSet) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	if register != nil {
+		register(fs)
+	}
+
+	base := config.Default()
+	if cli {
+		base.Log.Output = []string{logger.OutputConsole}
+		base.Log.Level = "warn"
+	}
+	cfg, err := config.LoadFrom(base, fs, args)
+	if err != nil {
+		logger.Fatal("%v", err)
+	}
+	return cfg, fs
+}
+
+// setupLogger 按配置初始化日志，输出到文件时会创建日志目录
+func setupLogger(cfg *config.Config, cli bool) {
+	logCfg, _ := cfg.Log.LoggerConfig() // 已在 Load 中校验
+	if cli {
+		logCfg.ConsoleWriter = os.Stderr
+	}
+	if err := logger.Setup(logCfg); err != nil {
+		logger.Fatal("初始化日志失败：%v", err)
+	}
+	logger.DebugS("生效配置", "config", cfg.Redacted())
+}
+
+// serviceConfig 转换为 service 包使用的配置
+func serviceConfig(c config.ServiceConfig) service.Config {
+	var campuses []service.Campus
+	for _, campus := range c.Campuses {
+		campuses = append(campuses, service.Campus{Name: campus.Name, Buildings: campus.Buildings})
+	}
+	return service.Config{
+		EmptyQueryTimeout:      time.Duration(c.EmptyQueryTimeout),
+		FullDayQueryTimeout:    time.Duration(c.FullDayQueryTimeout),
+		RoomQueryTimeout:       time.Duration(c.RoomQueryTimeout),
+		CalendarRefreshTimeout: time.Duration(c.CalendarRefreshTimeout),
+		CacheTTL:               time.Duration(c.CacheTTL),
+		StaleRetain:            time.Duration(c.StaleRetain),
+		Campuses:               campuses,
+		RecommendCount:         c.RecommendCount,
+	}
+}
+
+// newClient 按配置创建 CAS 客户端
+// interactive 为 true 时，manual 验证码模式改为在终端中输入
+func newClient(cfg *config.Config, interactive bool) (*cas.Client, *cas.ManualCaptchaSolver, error) {
+	// 账号角色：teacher / student，不设置时登录后自动识别
+	role, _ := cas.ParseRole(cfg.CAS.Role) // 已在 Load 中校验
+
+	clientOpts := []cas.ClientOption{
+		cas.WithTimeout(time.Duration(cfg.CAS.Timeout)),
+		cas.WithRole(role),
+		// 限制对教务系统的访问频率，避免给 zhjw.qfnu.edu.cn 造成压力
+		cas.WithRateLimit(cfg.CAS.RateLimit, cfg.CAS.RateBurst),
+		cas.WithMaxInFlight(cfg.CAS.MaxInFlight),
+		cas.WithMaxQueue(cfg.CAS.MaxQueue),
+		cas.WithObserver(metrics.CASObserver{}),
+		cas.WithLoginTimeout(loginTimeout(cfg)),
+	}
+	retryPolicy := cas.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = cfg.CAS.RetryAttempts
+	clientOpts = append(clientOpts, cas.WithRetryPolicy(retryPolicy))
+	clientOpts = append(clientOpts, cas.WithCircuitBreaker(cas.BreakerConfig{
+		FailureThreshold: cfg.CAS.BreakerThreshold,
+		OpenTimeout:      time.Duration(cfg.CAS.BreakerOpenTimeout),
+	}))
+
+	// 验证码识别方式：manual（管理页面人工输入）/ command（调用本地命令）
+	var manualSolver *cas.ManualCaptchaSolver
+	switch cfg.CAS.CaptchaSolver {
+	case "manual":
+		if interactive {
+			clientOpts = append(clientOpts, cas.WithCaptchaSolver(cas.CaptchaSolverFunc(solveCaptchaInTerminal)))
+			break
+		}
+		manualSolver = cas.NewManualCaptchaSolver(time.Duration(cfg.CAS.CaptchaTimeout))
+		clientOpts = append(clientOpts, cas.WithCaptchaSolver(manualSolver))
+		logger.Info("已启用人工验证码识别，需要时请访问 /admin/captcha 输入")
+		if cfg.Admin.Token == "" {
+			logger.Warn("未设置 ADMIN_TOKEN，验证码接口不可用，无法人工输入验证码")
+		}
+	case "command":
+		fields := strings.Fields(cfg.CAS.CaptchaCommand)
+		clientOpts = append(clientOpts, cas.WithCaptchaSolver(cas.NewCommandCaptchaSolver(fields[0], fields[1:]...)))
+	}
+
+	client, err := cas.NewClient(clientOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法创建 CAS 客户端：%w", err)
+	}
+	return client, manualSolver, nil
+}
+
+// loginTimeout 单次登录的超时，manual 模式下加上等待人工输入验证码的时间
+func loginTimeout(cfg *config.Config) time.Duration {
+	d := time.Duration(cfg.CAS.LoginTimeout)
+	if cfg.CAS.CaptchaSolver == "manual" {
+		d += time.Duration(cfg.CAS.CaptchaTimeout)
+	}
+	return d
+}
+
+// solveCaptchaInTerminal 将验证码图片保存到临时文件，并从标准输入读取答案
+func solveCaptchaInTerminal(ctx context.Context, image []byte) (string, error) {
+	f, err := os.CreateTemp("", "qfnu-captcha-*.jpg")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(image); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	fmt.Fprintf(os.Stderr, "登录需要验证码，图片已保存到 %s\n请输入验证码：", f.Name())
+	answer := make(chan string, 1)
+	go func() {
+		var s string
+		fmt.Fscanln(os.Stdin, &s)
+		answer <- strings.TrimSpace(s)
+	}()
+	select {
+	case s := <-answer:
+		if s == "" {
+			return "", fmt.Errorf("未输入验证码")
+		}
+		return s, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}